@@ -0,0 +1,92 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FieldError represents a validation error of a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError represents an error response returned by the backend API
+type APIError struct {
+	StatusCode int          `json:"-"`
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"fields,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("backend responded with %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Code != "" {
+		msg += " (code: " + e.Code + ")"
+	}
+	return msg
+}
+
+// IsNotFound reports whether the backend could not find the requested resource
+func (e *APIError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether the request conflicts with the current state of a resource
+func (e *APIError) IsConflict() bool {
+	return e.StatusCode == http.StatusConflict
+}
+
+// IsValidation reports whether the request failed the backend validation
+func (e *APIError) IsValidation() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+}
+
+// IsUnauthorized reports whether the backend rejected the credentials of the request
+func (e *APIError) IsUnauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// IsRateLimited reports whether the backend rejected the request because of rate limiting
+func (e *APIError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// newAPIError creates an APIError from an unexpected backend response
+func newAPIError(res *http.Response, resBody string) *APIError {
+	apiErr := &APIError{}
+	if err := json.Unmarshal([]byte(resBody), apiErr); err != nil {
+		apiErr = &APIError{Message: strings.TrimSpace(resBody)}
+	}
+	apiErr.StatusCode = res.StatusCode
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = res.Header.Get("X-Request-Id")
+	}
+	return apiErr
+}
+
+// RenderError writes a human friendly representation of err to w,
+// including the validation details and request id of backend API errors
+func RenderError(w io.Writer, err error) {
+	fmt.Fprintf(w, "error: %v\n", err)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	for _, f := range apiErr.Fields {
+		fmt.Fprintf(w, "  - %s: %s\n", f.Field, f.Message)
+	}
+	if apiErr.RequestID != "" {
+		fmt.Fprintf(w, "request id: %s\n", apiErr.RequestID)
+	}
+}
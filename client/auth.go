@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// BearerAuthScheme sends the stored access token as a standard bearer token
+	BearerAuthScheme = "bearer"
+	// APIKeyAuthScheme sends an API key in a custom header
+	APIKeyAuthScheme = "api-key"
+	// BasicAuthScheme sends a username and password using HTTP basic auth
+	BasicAuthScheme = "basic"
+
+	// APIKeyEnv and BasicPasswordEnv provide the auth secrets which are not configured,
+	// so that they stay out of the process list and of the profiles file
+	APIKeyEnv        = "EXPENSES_API_KEY"
+	BasicPasswordEnv = "EXPENSES_BASIC_PASSWORD"
+
+	defaultAPIKeyHeader = "X-API-Key"
+)
+
+// errNoRefreshToken is returned when an expired access token can not be refreshed
+var errNoRefreshToken = errors.New("no refresh token found")
+
+// AuthConfig represents the configuration of the auth scheme used for backend calls
+type AuthConfig struct {
+	Scheme   string `json:"scheme"`
+	Header   string `json:"header,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// AuthScheme represents a strategy for authorizing backend requests
+type AuthScheme interface {
+	Authorize(req *http.Request) error
+}
+
+// tokenRefresher is implemented by auth schemes which can renew the credentials a request was rejected with
+type tokenRefresher interface {
+	refresh(ctx context.Context, c HTTPClient, rejected *http.Request) error
+}
+
+// NewAuthScheme creates the AuthScheme described by the given config for a given profile
+func NewAuthScheme(profile string, cfg AuthConfig) (AuthScheme, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Scheme)) {
+	case "", BearerAuthScheme:
+		return bearerAuth{profile: profile, mu: &sync.Mutex{}}, nil
+	case APIKeyAuthScheme:
+		key := cfg.APIKey
+		if key == "" {
+			key = os.Getenv(APIKeyEnv)
+		}
+		if key == "" {
+			return nil, errors.New("api key must be provided for the api-key auth scheme, with --api-key-stdin or " + APIKeyEnv)
+		}
+		header := cfg.Header
+		if header == "" {
+			header = defaultAPIKeyHeader
+		}
+		return apiKeyAuth{header: header, key: key}, nil
+	case BasicAuthScheme:
+		if cfg.Username == "" {
+			return nil, errors.New("username must be provided for the basic auth scheme")
+		}
+		password := cfg.Password
+		if password == "" {
+			password = os.Getenv(BasicPasswordEnv)
+		}
+		return basicAuth{username: cfg.Username, password: password}, nil
+	default:
+		return nil, errors.New(
+			"auth scheme must be one of: " +
+				strings.Join([]string{BearerAuthScheme, APIKeyAuthScheme, BasicAuthScheme}, ","),
+		)
+	}
+}
+
+// ReadAuthSecrets reads the api key or the basic auth password of the config from stdin when asked to.
+// Secrets may also be left out of the config and provided with the APIKeyEnv or BasicPasswordEnv variables
+func ReadAuthSecrets(cfg *AuthConfig, apiKeyStdin, passwordStdin bool) error {
+	switch {
+	case apiKeyStdin && passwordStdin:
+		return errors.New("--api-key-stdin and --basic-password-stdin are mutually exclusive")
+	case apiKeyStdin && cfg.APIKey != "":
+		return errors.New("--api-key and --api-key-stdin are mutually exclusive")
+	case passwordStdin && cfg.Password != "":
+		return errors.New("--basic-password and --basic-password-stdin are mutually exclusive")
+	case apiKeyStdin:
+		secret, err := readPasswordLine(os.Stdin)
+		if err != nil {
+			return err
+		}
+		cfg.APIKey = secret
+	case passwordStdin:
+		secret, err := readPasswordLine(os.Stdin)
+		if err != nil {
+			return err
+		}
+		cfg.Password = secret
+	}
+	return nil
+}
+
+// bearerAuth authorizes requests with the access token stored for a profile
+type bearerAuth struct {
+	profile string
+	// mu serialises the refreshes, so that concurrent calls rejected together refresh the token once
+	mu *sync.Mutex
+}
+
+func (a bearerAuth) Authorize(req *http.Request) error {
+	credentials, err := readCredentials(a.profile)
+	if err != nil {
+		return errors.Wrap(err, "could not read credentials")
+	}
+	req.Header.Set("Authorization", "Bearer "+credentials.AccessToken)
+	return nil
+}
+
+// refresh exchanges the stored refresh token for new credentials and persists them,
+// unless another call already replaced the access token the request was rejected with
+func (a bearerAuth) refresh(ctx context.Context, c HTTPClient, rejected *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	credentials, err := readCredentials(a.profile)
+	if err != nil {
+		return errors.Wrap(err, "could not read credentials")
+	}
+	if rejected.Header.Get("Authorization") != "Bearer "+credentials.AccessToken {
+		return nil
+	}
+	if credentials.RefreshToken == "" {
+		return errNoRefreshToken
+	}
+
+	refreshed, err := c.Refresh(ctx, credentials.RefreshToken)
+	if err != nil {
+		return errors.Wrap(err, "could not refresh access token")
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = credentials.RefreshToken
+	}
+	if refreshed.UserID == "" {
+		refreshed.UserID = credentials.UserID
+	}
+	if err := saveCredentials(a.profile, refreshed); err != nil {
+		return errors.Wrap(err, "could not save refreshed credentials")
+	}
+	return nil
+}
+
+// apiKeyAuth authorizes requests with an API key sent in a custom header
+type apiKeyAuth struct {
+	header string
+	key    string
+}
+
+func (a apiKeyAuth) Authorize(req *http.Request) error {
+	req.Header.Set(a.header, a.key)
+	return nil
+}
+
+// basicAuth authorizes requests using HTTP basic auth
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a basicAuth) Authorize(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// setConfigHome points the config directory to a temp directory for the duration of a test
+func setConfigHome(t *testing.T) {
+	t.Helper()
+	old, ok := os.LookupEnv("XDG_CONFIG_HOME")
+	if err := os.Setenv("XDG_CONFIG_HOME", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("XDG_CONFIG_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	})
+}
+
+// rotatingBackend accepts only the latest access token and rotates the refresh token on every refresh
+type rotatingBackend struct {
+	mu        sync.Mutex
+	version   int
+	refreshes int
+}
+
+func (b *rotatingBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.URL.Path == refreshRoute.path {
+		var body refreshReqBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken != fmt.Sprintf("refresh-%d", b.version) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b.refreshes++
+		b.version++
+		_ = json.NewEncoder(w).Encode(Credentials{
+			AccessToken:  fmt.Sprintf("access-%d", b.version),
+			RefreshToken: fmt.Sprintf("refresh-%d", b.version),
+		})
+		return
+	}
+	if r.Header.Get("Authorization") != fmt.Sprintf("Bearer access-%d", b.version) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestBearerAuthRefreshesOnceForConcurrentCalls(t *testing.T) {
+	setConfigHome(t)
+	backend := &rotatingBackend{version: 1}
+	srv := httptest.NewServer(backend)
+	defer srv.Close()
+
+	// the stored access token is already expired on the backend
+	err := saveCredentials(DefaultProfileName, Credentials{AccessToken: "access-0", RefreshToken: "refresh-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := NewAuthScheme(DefaultProfileName, AuthConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewHTTPClient(srv.URL, auth, HTTPClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const calls = 8
+	errs := make([]error, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.Delete(context.Background(), fmt.Sprintf("e%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("delete %d: %v", i, err)
+		}
+	}
+	if backend.refreshes != 1 {
+		t.Errorf("got %d refreshes, want 1", backend.refreshes)
+	}
+	credentials, err := readCredentials(DefaultProfileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.RefreshToken != "refresh-2" {
+		t.Errorf("got stored refresh token %q, want refresh-2", credentials.RefreshToken)
+	}
+}
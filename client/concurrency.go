@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachConcurrently calls fn for every index in [0, n) using at most concurrency goroutines.
+// No new calls are started once the context is done
+func forEachConcurrently(ctx context.Context, n, concurrency int, fn func(i int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const appDirName = "expenses-cli"
+
+// configDir returns the per-user config directory of the CLI, creating it if needed
+func configDir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "could not find user home directory")
+		}
+		base = filepath.Join(home, ".config")
+	}
+	dir := filepath.Join(base, appDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", errors.Wrap(err, "could not create config directory")
+	}
+	return dir, nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and renames it over path,
+// so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "could not create temp file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "could not write temp file")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "could not sync temp file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "could not close temp file")
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return errors.Wrap(err, "could not set temp file permissions")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "could not rename temp file")
+	}
+	return nil
+}
@@ -1,19 +1,21 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // create represents the create command which creates a new expense
-func (s Switch) create() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) create() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		createCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		t := setTitleFlag(createCmd, false)
-		c := setCurrencyFlag(createCmd, false)
+		c := setCurrencyFlag(createCmd, s.registry, false)
 		p := setPriceFlag(createCmd, false)
+		key := setIdempotencyKeyFlag(createCmd)
+		quiet := setQuietFlag(createCmd)
 
 		if err := s.parseCmd(createCmd); err != nil {
 			return err
@@ -21,13 +23,22 @@ func (s Switch) create() func(string) error {
 		if err := s.checkArgs(createCmd, 3); err != nil {
 			return err
 		}
+		if t.value == "" || c.value == "" || !p.set {
+			return errors.New("title, currency and price of the expense must be provided")
+		}
+		price, err := p.money(knownCurrency(s.knownCurrencies, c.value))
+		if err != nil {
+			return err
+		}
 
-		err := s.client.Create(t.value, c.value, p.value)
+		expense, err := s.client.Create(ctx, t.value, price, key.value)
 		if err != nil {
 			return errors.Wrap(err, "could not create expense")
 		}
 
-		fmt.Println("expense created successfully")
-		return nil
+		if *quiet {
+			return s.printer.printID(expense.ID)
+		}
+		return s.printer.printExpense("expense created successfully", expense)
 	}
 }
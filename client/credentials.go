@@ -1,53 +1,144 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
-	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-const credentialsFileName = ".credentials.json"
+const (
+	credentialsDirName = "credentials"
+	// legacyCredentialsFileName is the credentials file older versions wrote to the working directory
+	legacyCredentialsFileName = ".credentials.json"
+	// legacyConfigCredentialsFileName is the single-profile credentials file in the config directory
+	legacyConfigCredentialsFileName = "credentials.json"
+)
 
 // Credentials represents the user credentials after successful login/signup
 type Credentials struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	UserID       string    `json:"user_id,omitempty"`
 }
 
-func saveCredentials(credentials Credentials) error {
-	_, err := os.Stat(credentialsFileName)
-	var credentialsFile *os.File
-	switch err.(type) {
-	case *os.PathError:
-		f, err := os.Create(credentialsFileName)
-		if err != nil {
-			return errors.Wrap(err, "could not create credentials file")
-		}
-		credentialsFile = f
-	case nil:
-		f, err := os.OpenFile(credentialsFileName, os.O_RDWR, os.ModePerm)
-		if err != nil {
-			return errors.Wrap(err, "could not open credentials file")
-		}
-		credentialsFile = f
+// credentialsPath returns the path of the credentials file of a given profile
+func credentialsPath(profile string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	credentialsDir := filepath.Join(dir, credentialsDirName)
+	if err := os.MkdirAll(credentialsDir, 0700); err != nil {
+		return "", errors.Wrap(err, "could not create credentials directory")
 	}
-	err = json.NewEncoder(credentialsFile).Encode(credentials)
+	return filepath.Join(credentialsDir, profile+".json"), nil
+}
+
+// saveCredentials saves the credentials of a given profile
+func saveCredentials(profile string, credentials Credentials) error {
+	path, err := credentialsPath(profile)
+	if err != nil {
+		return err
+	}
+	return writeCredentials(path, credentials)
+}
+
+// writeCredentials writes the credentials file readable by the current user only
+func writeCredentials(path string, credentials Credentials) error {
+	bs, err := json.Marshal(credentials)
 	if err != nil {
 		return errors.Wrap(err, "could not encode json credentials")
 	}
+	if err := writeFileAtomic(path, bs, 0600); err != nil {
+		return errors.Wrap(err, "could not write credentials file")
+	}
 	return nil
 }
 
-func readCredentials() (Credentials, error) {
-	var credentials Credentials
-	credentialsFile, err := os.Open(credentialsFileName)
+func readCredentials(profile string) (Credentials, error) {
+	path, err := credentialsPath(profile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	bs, err := ioutil.ReadFile(path)
 	if err != nil {
 		return Credentials{}, errors.Wrap(err, "could not open credentials file")
 	}
-	err = json.NewDecoder(credentialsFile).Decode(&credentials)
-	if err != nil && err != io.EOF {
+	var credentials Credentials
+	if len(bs) == 0 {
+		return credentials, nil
+	}
+	if err := json.Unmarshal(bs, &credentials); err != nil {
 		return Credentials{}, errors.Wrap(err, "could not decode json credentials")
 	}
 	return credentials, nil
 }
+
+// removeCredentials removes the credentials file of a given profile
+func removeCredentials(profile string) error {
+	path, err := credentialsPath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "could not remove credentials file")
+	}
+	return nil
+}
+
+// migrateLegacyCredentials moves the credentials file from the working directory,
+// or the single-profile file from the config directory, into the default profile credentials.
+// It only runs while the default profile has no credentials file, and a legacy file is only removed
+// once migrated, so that unrelated files using the same name are left untouched
+func migrateLegacyCredentials() error {
+	path, err := credentialsPath(DefaultProfileName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	legacyPaths := []string{
+		filepath.Join(dir, legacyConfigCredentialsFileName),
+		legacyCredentialsFileName,
+	}
+	for _, legacyPath := range legacyPaths {
+		credentials, ok := readLegacyCredentials(legacyPath)
+		if !ok {
+			continue
+		}
+		if err := writeCredentials(path, credentials); err != nil {
+			return errors.Wrap(err, "could not migrate legacy credentials")
+		}
+		if err := os.Remove(legacyPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "could not remove legacy credentials file")
+		}
+		return nil
+	}
+	return nil
+}
+
+// readLegacyCredentials reads a legacy credentials file and reports whether it holds an access token
+func readLegacyCredentials(path string) (Credentials, bool) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Credentials{}, false
+	}
+	// older versions could leave stale trailing bytes, so only the first json value is kept
+	var credentials Credentials
+	if err := json.NewDecoder(bytes.NewReader(bs)).Decode(&credentials); err != nil {
+		return Credentials{}, false
+	}
+	return credentials, credentials.AccessToken != ""
+}
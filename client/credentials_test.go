@@ -0,0 +1,138 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// chdirTemp changes the working directory to a temp directory for the duration of a test
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestMigrateLegacyCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		legacy       string
+		stored       *Credentials
+		wantToken    string
+		wantLegacy   bool
+		wantNoStored bool
+	}{
+		{
+			name:       "legacy credentials",
+			legacy:     `{"access_token":"legacy","expires_at":"0001-01-01T00:00:00Z"}`,
+			wantToken:  "legacy",
+			wantLegacy: false,
+		},
+		{
+			name:       "stale trailing bytes",
+			legacy:     `{"access_token":"legacy"}"}`,
+			wantToken:  "legacy",
+			wantLegacy: false,
+		},
+		{
+			name:         "unrelated file",
+			legacy:       `{"type":"service_account","project_id":"p"}`,
+			wantLegacy:   true,
+			wantNoStored: true,
+		},
+		{
+			name:         "invalid json",
+			legacy:       `not json`,
+			wantLegacy:   true,
+			wantNoStored: true,
+		},
+		{
+			name:       "default profile already has credentials",
+			legacy:     `{"access_token":"legacy"}`,
+			stored:     &Credentials{AccessToken: "stored"},
+			wantToken:  "stored",
+			wantLegacy: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfigHome(t)
+			chdirTemp(t)
+			if err := ioutil.WriteFile(legacyCredentialsFileName, []byte(tt.legacy), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.stored != nil {
+				if err := saveCredentials(DefaultProfileName, *tt.stored); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := migrateLegacyCredentials(); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := os.Stat(legacyCredentialsFileName)
+			if gotLegacy := err == nil; gotLegacy != tt.wantLegacy {
+				t.Errorf("got legacy file kept %v, want %v", gotLegacy, tt.wantLegacy)
+			}
+			credentials, err := readCredentials(DefaultProfileName)
+			if tt.wantNoStored {
+				if !os.IsNotExist(errors.Cause(err)) {
+					t.Errorf("got credentials %+v and error %v, want no credentials file", credentials, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if credentials.AccessToken != tt.wantToken {
+				t.Errorf("got access token %q, want %q", credentials.AccessToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyCredentialsConcurrently(t *testing.T) {
+	setConfigHome(t)
+	chdirTemp(t)
+	dir, err := configDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyPath := filepath.Join(dir, legacyConfigCredentialsFileName)
+	if err := ioutil.WriteFile(legacyPath, []byte(`{"access_token":"legacy"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	const calls = 8
+	errs := make([]error, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = migrateLegacyCredentials()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("migration %d: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Errorf("got legacy file kept, want it removed")
+	}
+}
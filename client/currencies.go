@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+// currencyCommands lists the commands which validate or format currencies,
+// only these fetch the backend currencies when the profile asks for it
+var currencyCommands = map[string]bool{
+	"get-all":    true,
+	"get-by-ids": true,
+	"create":     true,
+	"update":     true,
+	"import":     true,
+	"export":     true,
+	"currencies": true,
+}
+
+// CurrenciesConfig restricts or extends the ISO 4217 currencies accepted by a profile
+type CurrenciesConfig struct {
+	// Allowed restricts the accepted currencies to these codes, every currency is accepted when empty
+	Allowed []string `json:"allowed,omitempty"`
+	// Extra adds currencies which are not part of ISO 4217, e.g. crypto currencies
+	Extra []currency.Currency `json:"extra,omitempty"`
+	// FromBackend restricts the accepted currencies to the ones listed by the currencies endpoint of the backend
+	FromBackend bool `json:"from_backend,omitempty"`
+}
+
+// newKnownCurrencies creates the table of every currency an amount may be in: the ISO 4217 currencies
+// extended with the extra currencies of the profile, and later with the backend ones. Unlike the registry
+// of the currency flags it is never restricted, so that amounts keep their decimal places whatever the profile accepts
+func newKnownCurrencies(cfg CurrenciesConfig) (*currency.Registry, error) {
+	known := currency.ISO4217()
+	if err := known.Add(cfg.Extra...); err != nil {
+		return nil, errors.Wrap(err, "invalid extra currency")
+	}
+	return known, nil
+}
+
+// knownCurrency finds a currency in the table of known currencies, unknown currencies have 2 decimal places
+func knownCurrency(known *currency.Registry, code string) currency.Currency {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if c, ok := known.Lookup(code); ok {
+		return c
+	}
+	return currency.Currency{Code: code, MinorUnits: 2}
+}
+
+// newCurrencyRegistry creates the ISO 4217 registry extended and restricted by the profile configuration
+func newCurrencyRegistry(cfg CurrenciesConfig) (*currency.Registry, error) {
+	registry := currency.ISO4217()
+	if err := registry.Add(cfg.Extra...); err != nil {
+		return nil, errors.Wrap(err, "invalid extra currency")
+	}
+	if len(cfg.Allowed) > 0 {
+		if err := registry.Restrict(cfg.Allowed...); err != nil {
+			return nil, errors.Wrap(err, "invalid allowed currencies")
+		}
+	}
+	return registry, nil
+}
+
+// loadBackendCurrencies restricts the registry to the currencies accepted by the backend,
+// currencies unknown to the registry are added with their known metadata, or else the backend one
+// which is then added to the known currencies as well
+func loadBackendCurrencies(ctx context.Context, client BackendHTTPClient, registry, known *currency.Registry) error {
+	currencies, err := client.Currencies(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not fetch currencies")
+	}
+	codes := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		if _, ok := registry.Lookup(c.Code); !ok {
+			if k, ok := known.Lookup(c.Code); ok {
+				c = k
+			} else if err := known.Add(c); err != nil {
+				return errors.Wrap(err, "invalid backend currency")
+			}
+			if err := registry.Add(c); err != nil {
+				return errors.Wrap(err, "invalid backend currency")
+			}
+		}
+		codes = append(codes, c.Code)
+	}
+	return registry.Restrict(codes...)
+}
+
+// currencies represents the currencies command which lists the accepted currencies
+func (s Switch) currencies() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
+		currenciesCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
+		if err := s.parseCmd(currenciesCmd); err != nil {
+			return err
+		}
+
+		currencies := s.registry.All()
+		switch s.printer.format {
+		case TableOutput:
+			tw := tabwriter.NewWriter(s.printer.out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "CODE\tNUMERIC\tNAME\tSYMBOL\tDECIMALS")
+			for _, c := range currencies {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", c.Code, c.Numeric, c.Name, c.Symbol, c.MinorUnits)
+			}
+			return tw.Flush()
+		case CSVOutput:
+			w := csv.NewWriter(s.printer.out)
+			_ = w.Write([]string{"code", "numeric", "name", "symbol", "minor_units"})
+			for _, c := range currencies {
+				_ = w.Write([]string{c.Code, c.Numeric, c.Name, c.Symbol, strconv.Itoa(c.MinorUnits)})
+			}
+			w.Flush()
+			return errors.Wrap(w.Error(), "could not write csv")
+		default:
+			return s.printer.printValue(currencies)
+		}
+	}
+}
+
+// unsupportedCurrencyError lists the accepted currencies when there are only a few of them,
+// otherwise it points to the currencies command
+func unsupportedCurrencyError(registry *currency.Registry, code string) error {
+	currencies := registry.All()
+	if len(currencies) > 10 {
+		return errors.Errorf("currency '%s' is not supported, see the currencies command", code)
+	}
+	codes := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		codes = append(codes, c.Code)
+	}
+	return errors.New("currency must be one of: " + strings.Join(codes, ","))
+}
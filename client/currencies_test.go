@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+func TestRestrictedCurrenciesKeepMinorUnits(t *testing.T) {
+	registry, err := newCurrencyRegistry(CurrenciesConfig{Allowed: []string{"USD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := currencyFlag{registry: registry}
+	if err := c.Set("jpy"); err == nil {
+		t.Error("got no error, want JPY to be rejected by the currency flag")
+	}
+	if err := c.Set("usd"); err != nil {
+		t.Errorf("got error %v, want USD to be accepted by the currency flag", err)
+	}
+
+	known, err := newKnownCurrencies(CurrenciesConfig{Allowed: []string{"USD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v expenseJSON
+	if err := json.Unmarshal([]byte(`{"id":"e1","currency":"JPY","price":1000}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.expense(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Price.Amount != 1000 || e.Price.Decimal() != "1000" {
+		t.Errorf("got JPY amount %d (%s), want 1000", e.Price.Amount, e.Price.Decimal())
+	}
+}
+
+func TestLoadBackendCurrencies(t *testing.T) {
+	backend := &fakeBackend{currencies: []currency.Currency{
+		{Code: "EUR", MinorUnits: 2, Name: "Euro"},
+		{Code: "XBT", MinorUnits: 8, Name: "Bitcoin"},
+	}}
+	registry, err := newCurrencyRegistry(CurrenciesConfig{Allowed: []string{"USD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, err := newKnownCurrencies(CurrenciesConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loadBackendCurrencies(context.Background(), backend, registry, known); err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	for _, c := range registry.All() {
+		codes = append(codes, c.Code)
+	}
+	if len(codes) != 2 || codes[0] != "EUR" || codes[1] != "XBT" {
+		t.Errorf("got currencies %v, want [EUR XBT]", codes)
+	}
+	if got := knownCurrency(known, "XBT").MinorUnits; got != 8 {
+		t.Errorf("got %d XBT minor units, want 8", got)
+	}
+	if _, ok := currency.ISO4217().Lookup("XBT"); ok {
+		t.Error("got XBT in a new ISO 4217 registry, want the backend currencies kept in the known currencies only")
+	}
+}
+
+func TestKnownCurrenciesExtra(t *testing.T) {
+	cfg := CurrenciesConfig{Extra: []currency.Currency{{Code: "xbt", MinorUnits: 8, Name: "Bitcoin"}}}
+	known, err := newKnownCurrencies(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Extra[0].Code != "xbt" {
+		t.Errorf("got extra currency code %s, want the profile configuration left untouched", cfg.Extra[0].Code)
+	}
+
+	v := expenseJSON{ID: "e1", Currency: "XBT", Price: "0.123456789"}
+	e, err := v.expense(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Price.Decimal() != "0.12345679" {
+		t.Errorf("got XBT price %s, want 0.12345679", e.Price.Decimal())
+	}
+
+	other, err := newKnownCurrencies(CurrenciesConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := v.expense(other); err != nil || e.Price.Decimal() != "0.12" {
+		t.Errorf("got XBT price %s (error %v) without the extra currency, want 0.12", e.Price.Decimal(), err)
+	}
+}
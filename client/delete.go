@@ -1,17 +1,26 @@
 package client
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/term"
 )
 
-// delete represents the delete command which delete one expense by a given id
-func (s Switch) delete() func(string) error {
-	return func(cmdName string) error {
+// delete represents the delete command which deletes every expense of the given ids
+func (s Switch) delete() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		deleteCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		ids := setIDsFlag(deleteCmd)
+		idsFrom := deleteCmd.String("ids-from", "", "Read expense ids from a file, or from stdin with -, separated by whitespace or commas")
+		yes := deleteCmd.Bool("yes", false, "Skip the confirmation prompt")
+		concurrency := setConcurrencyFlag(deleteCmd, "Maximum number of expenses deleted concurrently")
 
 		if err := s.parseCmd(deleteCmd); err != nil {
 			return err
@@ -19,16 +28,105 @@ func (s Switch) delete() func(string) error {
 		if err := s.checkArgs(deleteCmd, 1); err != nil {
 			return err
 		}
+		if *idsFrom != "" {
+			if err := readIDs(*idsFrom, ids); err != nil {
+				return err
+			}
+		}
 		if len(ids.value) == 0 {
 			return errors.New("id of the expense must be provided")
 		}
+		if !*yes {
+			if err := confirmDelete(len(ids.value), *idsFrom == "-"); err != nil {
+				return err
+			}
+		}
+
+		results := make([]commandResult, len(ids.value))
+		forEachConcurrently(ctx, len(ids.value), concurrency.value, func(i int) {
+			id := ids.value[i]
+			if err := s.client.Delete(ctx, id); err != nil {
+				results[i] = commandResult{
+					ID:      id,
+					Status:  "failed",
+					Message: fmt.Sprintf("could not delete expense with id: %s: %v", id, err),
+				}
+				return
+			}
+			results[i] = commandResult{
+				ID:      id,
+				Status:  "deleted",
+				Message: fmt.Sprintf("expense with id: %s deleted successfully", id),
+			}
+		})
 
-		err := s.client.Delete(ids.value[0])
+		var failed int
+		for i, r := range results {
+			if r.Status == "" {
+				results[i] = commandResult{
+					ID:      ids.value[i],
+					Status:  "skipped",
+					Message: fmt.Sprintf("expense with id: %s was not deleted: %v", ids.value[i], ctx.Err()),
+				}
+			}
+			if results[i].Status != "deleted" {
+				failed++
+			}
+		}
+		if err := s.printer.printResults(results); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "could not delete expenses")
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d expense(s) could not be deleted", failed, len(results))
+		}
+		return nil
+	}
+}
+
+// readIDs reads whitespace or comma separated ids from a file, or from stdin when path is -
+func readIDs(path string, ids *idsFlag) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
 		if err != nil {
-			return errors.Wrap(err, "could not delete expense")
+			return errors.Wrap(err, "could not open ids file")
 		}
+		defer f.Close()
+		r = f
+	}
 
-		fmt.Printf("expense with id: %s deleted successfully\n", ids.value[0])
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		for _, id := range strings.Split(scanner.Text(), ",") {
+			if strings.TrimSpace(id) == "" {
+				continue
+			}
+			if err := ids.Set(id); err != nil {
+				return errors.Wrapf(err, "invalid id '%s'", id)
+			}
+		}
+	}
+	return errors.Wrap(scanner.Err(), "could not read ids")
+}
+
+// confirmDelete asks the user to confirm the deletion on the terminal
+func confirmDelete(count int, idsFromStdin bool) error {
+	if idsFromStdin || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return errors.New("deletion must be confirmed with --yes when not running on an interactive terminal")
+	}
+	fmt.Fprintf(os.Stderr, "delete %d expense(s)? [y/N]: ", count)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "could not read confirmation")
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
 		return nil
+	default:
+		return errors.New("deletion aborted")
 	}
 }
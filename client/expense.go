@@ -0,0 +1,152 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+// Expense represents an expense as returned by the backend API
+type Expense struct {
+	ID        string
+	Title     string
+	Price     Money
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// expenseJSON represents the json encoding of an expense, the price being an exact json number
+type expenseJSON struct {
+	ID        string      `json:"id"`
+	Title     string      `json:"title"`
+	Currency  string      `json:"currency"`
+	Price     json.Number `json:"price"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Currency returns the currency of the expense price
+func (e Expense) Currency() string {
+	return e.Price.Currency
+}
+
+func (e Expense) String() string {
+	return fmt.Sprintf(
+		"id: %s, title: %s, price: %s, created at: %s, updated at: %s",
+		e.ID,
+		e.Title,
+		e.Price,
+		e.CreatedAt.Format(time.RFC3339),
+		e.UpdatedAt.Format(time.RFC3339),
+	)
+}
+
+func (e Expense) MarshalJSON() ([]byte, error) {
+	return json.Marshal(expenseJSON{
+		ID:        e.ID,
+		Title:     e.Title,
+		Currency:  e.Price.Currency,
+		Price:     json.Number(e.Price.Decimal()),
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	})
+}
+
+// expense decodes the expense, the price is rounded to the decimal places of its currency in the known currencies
+func (v expenseJSON) expense(known *currency.Registry) (Expense, error) {
+	price, err := moneyFromNumber(v.Price.String(), knownCurrency(known, v.Currency))
+	if err != nil {
+		return Expense{}, errors.Wrapf(err, "could not decode the price of expense with id: %s", v.ID)
+	}
+	return Expense{
+		ID:        v.ID,
+		Title:     v.Title,
+		Price:     price,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.UpdatedAt,
+	}, nil
+}
+
+// decodeExpenses decodes a list of expenses with the known currencies
+func decodeExpenses(vs []expenseJSON, known *currency.Registry) ([]Expense, error) {
+	expenses := make([]Expense, 0, len(vs))
+	for _, v := range vs {
+		e, err := v.expense(known)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, nil
+}
+
+// ExpensesPage represents a paginated list of expenses as returned by the backend API.
+// Backends paginate either by page number (Total, NextPage) or by cursor (NextCursor)
+type ExpensesPage struct {
+	Expenses   []Expense `json:"expenses"`
+	Page       int       `json:"page"`
+	PageSize   int       `json:"page_size"`
+	Total      int       `json:"total"`
+	NextPage   int       `json:"next_page,omitempty"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// ExpensesQuery represents the query of the get-all API endpoint, zero values are not sent
+type ExpensesQuery struct {
+	Page     int
+	PageSize int
+	Cursor   string
+
+	From     time.Time
+	To       time.Time
+	Currency string
+	// MinPrice and MaxPrice are exact decimal amounts, e.g. 19.99
+	MinPrice      string
+	MaxPrice      string
+	TitleContains string
+	// Sort lists the sort fields, prefixed by - for descending order
+	Sort []string
+}
+
+// values encodes the query as URL query parameters
+func (q ExpensesQuery) values() url.Values {
+	params := url.Values{}
+	if q.Page > 0 {
+		params.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PageSize > 0 {
+		params.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	if q.Cursor != "" {
+		params.Set("cursor", q.Cursor)
+	}
+	if !q.From.IsZero() {
+		params.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		params.Set("to", q.To.Format(time.RFC3339))
+	}
+	if q.Currency != "" {
+		params.Set("currency", q.Currency)
+	}
+	if q.MinPrice != "" {
+		params.Set("min_price", q.MinPrice)
+	}
+	if q.MaxPrice != "" {
+		params.Set("max_price", q.MaxPrice)
+	}
+	if q.TitleContains != "" {
+		params.Set("title_contains", q.TitleContains)
+	}
+	if len(q.Sort) > 0 {
+		params.Set("sort", strings.Join(q.Sort, ","))
+	}
+	return params
+}
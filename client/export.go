@@ -0,0 +1,460 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+const (
+	csvExportFormat     = "csv"
+	xlsxCSVExportFormat = "xlsx-csv"
+	jsonExportFormat    = "json"
+	ndjsonExportFormat  = "ndjson"
+	ofxExportFormat     = "ofx"
+
+	ofxTimeLayout = "20060102150405"
+	// ofxNameLength is the maximum length of the NAME element of an OFX transaction
+	ofxNameLength = 32
+)
+
+var exportFormats = []string{csvExportFormat, xlsxCSVExportFormat, jsonExportFormat, ndjsonExportFormat, ofxExportFormat}
+
+// export represents the export command which writes every expense matching the filters to a file or stdout.
+// Expenses are streamed page by page, so that memory stays flat regardless of the number of expenses
+func (s Switch) export() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
+		exportCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
+		file := exportCmd.String("file", "", "File to export the expenses to (defaults to stdout)")
+		format := exportCmd.String("format", "", "Export format: "+strings.Join(exportFormats, ",")+" (detected from the file extension by default)")
+		columns := setColumnsFlag(exportCmd)
+		concurrency := setConcurrencyFlag(exportCmd, "Maximum number of pages prefetched concurrently")
+		filters := setExpensesFilterFlags(exportCmd, s.registry)
+		convertTo, rates := setConvertFlags(exportCmd, s.registry)
+
+		if err := s.parseCmd(exportCmd); err != nil {
+			return err
+		}
+		f, err := exportFormat(*file, *format)
+		if err != nil {
+			return err
+		}
+		defaultColumns := columns.String() == strings.Join(expenseCSVHeader, ",")
+		if f == ofxExportFormat && !defaultColumns {
+			return errors.New("columns can not be selected for the ofx format")
+		}
+		c, err := converter(convertTo.value, *rates, s.knownCurrencies)
+		if err != nil {
+			return err
+		}
+		switch {
+		case c != nil && defaultColumns:
+			columns.value = convertedExpenseCSVHeader
+		case c == nil && columns.converted():
+			return errors.New("converted columns can only be exported along with convert-to")
+		}
+
+		query := ExpensesQuery{Page: defaultPage, PageSize: maxPageSize}
+		if err := filters.apply(&query); err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		var tmp *os.File
+		if *file != "" {
+			// the expenses are written to a temp file renamed on success, so that a failed export
+			// never leaves a truncated file behind
+			tmp, err = ioutil.TempFile(filepath.Dir(*file), "."+filepath.Base(*file)+".tmp-*")
+			if err != nil {
+				return errors.Wrap(err, "could not create export file")
+			}
+			defer os.Remove(tmp.Name())
+			defer tmp.Close()
+			out = tmp
+		}
+
+		buf := bufio.NewWriter(out)
+		w := newExportWriter(buf, f, columns.value, query, c, s.knownCurrencies)
+		it := NewExpensesIterator(ctx, s.client, query, 0, concurrency.value)
+		defer it.Close()
+		for it.Next() {
+			if err := w.write(it.Expense()); err != nil {
+				return errors.Wrap(err, "could not export expenses")
+			}
+		}
+		if err := it.Err(); err != nil {
+			return errors.Wrap(err, "could not fetch expenses")
+		}
+		if err := w.close(); err != nil {
+			return errors.Wrap(err, "could not export expenses")
+		}
+		if err := buf.Flush(); err != nil {
+			return errors.Wrap(err, "could not export expenses")
+		}
+
+		if tmp == nil {
+			return nil
+		}
+		if err := tmp.Close(); err != nil {
+			return errors.Wrap(err, "could not close export file")
+		}
+		if err := os.Chmod(tmp.Name(), 0644); err != nil {
+			return errors.Wrap(err, "could not set export file permissions")
+		}
+		if err := os.Rename(tmp.Name(), *file); err != nil {
+			return errors.Wrap(err, "could not write export file")
+		}
+		message := fmt.Sprintf("%d expense(s) exported to: %s", w.count, *file)
+		if c != nil {
+			message += fmt.Sprintf(" (total: %s)", w.convertedTotal)
+		}
+		return s.printer.printResult(commandResult{
+			Status:  "exported",
+			Message: message,
+		})
+	}
+}
+
+// exportFormat returns the export format, detecting it from the file extension when not provided
+func exportFormat(file, format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			format = jsonExportFormat
+		case ".ndjson", ".jsonl":
+			format = ndjsonExportFormat
+		case ".ofx":
+			format = ofxExportFormat
+		default:
+			format = csvExportFormat
+		}
+	}
+	for _, f := range exportFormats {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", errors.New("format must be one of: " + strings.Join(exportFormats, ","))
+}
+
+// isExpenseColumn reports whether a column is an exportable expense field, converted fields included
+func isExpenseColumn(column string) bool {
+	for _, c := range convertedExpenseCSVHeader {
+		if column == c {
+			return true
+		}
+	}
+	return false
+}
+
+// isConvertedColumn reports whether a column is a field of the converted price
+func isConvertedColumn(column string) bool {
+	switch column {
+	case "converted_price", "converted_currency", "rate":
+		return true
+	default:
+		return false
+	}
+}
+
+// expenseColumn returns the value of an expense field formatted as text,
+// the converted fields are empty unless the expense was converted
+func expenseColumn(e ConvertedExpense, column string) string {
+	switch column {
+	case "id":
+		return e.ID
+	case "title":
+		return e.Title
+	case "price":
+		return e.Price.Decimal()
+	case "currency":
+		return e.Currency()
+	case "created_at":
+		return formatTime(e.CreatedAt)
+	case "updated_at":
+		return formatTime(e.UpdatedAt)
+	case "converted_price":
+		if e.Converted.Currency == "" {
+			return ""
+		}
+		return e.Converted.Decimal()
+	case "converted_currency":
+		return e.Converted.Currency
+	case "rate":
+		return e.Rate
+	default:
+		return ""
+	}
+}
+
+// exportWriter writes expenses one by one in an export format,
+// with a converter the converted fields can be exported and the ofx statement is in the converted currency
+type exportWriter struct {
+	out       io.Writer
+	format    string
+	columns   []string
+	query     ExpensesQuery
+	converter *Converter
+	// known gives the decimal places of the ofx balance
+	known *currency.Registry
+	cw    *csv.Writer
+	count int
+	// convertedTotal sums the converted prices
+	convertedTotal Money
+
+	// ofxCurrency and ofxTotal keep the statement currency and balance of the ofx format
+	ofxCurrency string
+	ofxTotal    Money
+}
+
+func newExportWriter(out io.Writer, format string, columns []string, query ExpensesQuery, converter *Converter, known *currency.Registry) *exportWriter {
+	cw := csv.NewWriter(out)
+	// spreadsheet applications expect a byte order mark and CRLF line endings to detect utf-8 csv
+	cw.UseCRLF = format == xlsxCSVExportFormat
+	w := &exportWriter{
+		out:         out,
+		format:      format,
+		columns:     columns,
+		query:       query,
+		converter:   converter,
+		known:       known,
+		cw:          cw,
+		ofxCurrency: query.Currency,
+	}
+	if converter != nil {
+		w.convertedTotal = converter.zero()
+		w.ofxCurrency = converter.Currency()
+	}
+	return w
+}
+
+func (w *exportWriter) write(expense Expense) error {
+	e := ConvertedExpense{Expense: expense}
+	if w.converter != nil {
+		converted, err := w.converter.Convert(expense)
+		if err != nil {
+			return err
+		}
+		if w.convertedTotal, err = w.convertedTotal.Add(converted.Converted); err != nil {
+			return err
+		}
+		e = converted
+	}
+	defer func() { w.count++ }()
+
+	switch w.format {
+	case csvExportFormat, xlsxCSVExportFormat:
+		if w.count == 0 {
+			w.writeCSVHeader()
+		}
+		record := make([]string, len(w.columns))
+		for i, column := range w.columns {
+			record[i] = expenseColumn(e, column)
+			if w.format == xlsxCSVExportFormat {
+				record[i] = escapeSpreadsheetFormula(record[i])
+			}
+		}
+		return w.cw.Write(record)
+	case jsonExportFormat, ndjsonExportFormat:
+		bs, err := w.marshalJSON(e)
+		if err != nil {
+			return errors.Wrap(err, "could not encode json")
+		}
+		switch {
+		case w.format == ndjsonExportFormat:
+			_, err = fmt.Fprintf(w.out, "%s\n", bs)
+		case w.count == 0:
+			_, err = fmt.Fprintf(w.out, "[\n%s", bs)
+		default:
+			_, err = fmt.Fprintf(w.out, ",\n%s", bs)
+		}
+		return err
+	default:
+		price := e.Price
+		if w.converter != nil {
+			price = e.Converted
+		}
+		return w.writeOFXTransaction(e.Expense, price)
+	}
+}
+
+func (w *exportWriter) close() error {
+	switch w.format {
+	case csvExportFormat, xlsxCSVExportFormat:
+		if w.count == 0 {
+			w.writeCSVHeader()
+		}
+		w.cw.Flush()
+		return w.cw.Error()
+	case jsonExportFormat:
+		if w.count == 0 {
+			_, err := fmt.Fprintln(w.out, "[]")
+			return err
+		}
+		_, err := fmt.Fprintln(w.out, "\n]")
+		return err
+	case ndjsonExportFormat:
+		return nil
+	default:
+		if w.count == 0 {
+			if err := w.writeOFXHeader(); err != nil {
+				return err
+			}
+		}
+		return w.writeOFXFooter()
+	}
+}
+
+func (w *exportWriter) writeCSVHeader() {
+	if w.format == xlsxCSVExportFormat {
+		fmt.Fprint(w.out, "\ufeff")
+	}
+	_ = w.cw.Write(w.columns)
+}
+
+// marshalJSON encodes the selected fields of an expense as a json object, keeping the columns order
+func (w *exportWriter) marshalJSON(e ConvertedExpense) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, column := range w.columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		var v interface{} = expenseColumn(e, column)
+		switch column {
+		case "price", "converted_price", "rate":
+			v = json.Number(expenseColumn(e, column))
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return []byte(b.String()), nil
+}
+
+// escapeSpreadsheetFormula prevents spreadsheet applications from evaluating a text field as a formula
+func escapeSpreadsheetFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "'" + v
+		}
+	}
+	return v
+}
+
+// writeOFXHeader writes the OFX 2 credit card statement header. OFX statements have a single currency,
+// which is the currency filter or else the currency of the first exported expense
+func (w *exportWriter) writeOFXHeader() error {
+	currency := w.ofxCurrency
+	if currency == "" {
+		currency = "USD"
+	}
+	start, end := w.query.From, w.query.To
+	if start.IsZero() {
+		start = time.Unix(0, 0)
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	now := time.Now().UTC().Format(ofxTimeLayout)
+
+	_, err := fmt.Fprintf(w.out, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<CREDITCARDMSGSRSV1><CCSTMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<CCSTMTRS><CURDEF>%s</CURDEF><CCACCTFROM><ACCTID>expenses</ACCTID></CCACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`,
+		now,
+		currency,
+		start.UTC().Format(ofxTimeLayout),
+		end.UTC().Format(ofxTimeLayout),
+	)
+	w.ofxCurrency = currency
+	w.ofxTotal = zeroMoney(knownCurrency(w.known, currency))
+	return err
+}
+
+// writeOFXTransaction writes an expense as a debit transaction of the statement for a given price,
+// which is the converted price when converting
+func (w *exportWriter) writeOFXTransaction(e Expense, price Money) error {
+	if w.count == 0 {
+		if w.ofxCurrency == "" {
+			w.ofxCurrency = price.Currency
+		}
+		if err := w.writeOFXHeader(); err != nil {
+			return err
+		}
+	}
+	if price.Currency != w.ofxCurrency {
+		return fmt.Errorf(
+			"ofx statements have a single currency, expense with id: %s is in %s instead of %s, filter with --currency or use --convert-to",
+			e.ID,
+			price.Currency,
+			w.ofxCurrency,
+		)
+	}
+	total, err := w.ofxTotal.Add(price.Neg())
+	if err != nil {
+		return err
+	}
+	w.ofxTotal = total
+
+	name := []rune(e.Title)
+	if len(name) > ofxNameLength {
+		name = name[:ofxNameLength]
+	}
+	_, err = fmt.Fprintf(
+		w.out,
+		"<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>\n",
+		e.CreatedAt.UTC().Format(ofxTimeLayout),
+		price.Neg().Decimal(),
+		xmlEscape(e.ID),
+		xmlEscape(string(name)),
+		xmlEscape(e.Title),
+	)
+	return err
+}
+
+// writeOFXFooter closes the statement with the balance of the exported expenses
+func (w *exportWriter) writeOFXFooter() error {
+	_, err := fmt.Fprintf(w.out, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`,
+		w.ofxTotal.Decimal(),
+		time.Now().UTC().Format(ofxTimeLayout),
+	)
+	return err
+}
+
+// xmlEscape escapes a text to be written inside an xml element
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+func TestExportWriterOFX(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    ExpensesQuery
+		expenses []Expense
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "currency of the first expense",
+			expenses: []Expense{
+				{ID: "e1", Title: "a", Price: testMoney(1000, "EUR")},
+				{ID: "e2", Title: "b", Price: testMoney(250, "EUR")},
+			},
+			want: []string{"<CURDEF>EUR</CURDEF>", "<TRNAMT>-10.00</TRNAMT>", "<TRNAMT>-2.50</TRNAMT>", "<BALAMT>-12.50</BALAMT>"},
+		},
+		{
+			name:     "currency filter",
+			query:    ExpensesQuery{Currency: "JPY"},
+			expenses: []Expense{{ID: "e1", Title: "a", Price: testMoney(1000, "JPY")}},
+			want:     []string{"<CURDEF>JPY</CURDEF>", "<TRNAMT>-1000</TRNAMT>", "<BALAMT>-1000</BALAMT>"},
+		},
+		{
+			name: "mixed currencies",
+			expenses: []Expense{
+				{ID: "e1", Title: "a", Price: testMoney(1000, "EUR")},
+				{ID: "e2", Title: "b", Price: testMoney(250, "USD")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newExportWriter(&out, ofxExportFormat, nil, tt.query, nil, currency.ISO4217())
+			var err error
+			for _, e := range tt.expenses {
+				if err = w.write(e); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = w.close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("got statement without %s:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
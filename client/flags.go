@@ -6,10 +6,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
 )
 
 // titleFlag represents the title flag
@@ -45,10 +48,11 @@ func setTitleFlag(f *flag.FlagSet, optional bool) *titleFlag {
 	return &t
 }
 
-// currencyFlag represents the currency flag
+// currencyFlag represents the currency flag, restricted to the currencies of a registry
 type currencyFlag struct {
 	value    string
 	optional bool
+	registry *currency.Registry
 }
 
 func (c currencyFlag) String() string {
@@ -56,24 +60,22 @@ func (c currencyFlag) String() string {
 }
 
 func (c *currencyFlag) Set(currency string) error {
-	currencies := []string{"USD", "EUR", "GBP", "MDL"}
 	currency = strings.TrimSpace(strings.ToUpper(currency))
 	if c.optional && currency == "" {
 		return nil
 	}
-	switch currency {
-	case currencies[0], currencies[1], currencies[2], currencies[3]:
-		c.value = currency
-		return nil
-	default:
-		return errors.New("currency must be one of: " + strings.Join(currencies, ","))
+	if _, ok := c.registry.Lookup(currency); !ok {
+		return unsupportedCurrencyError(c.registry, currency)
 	}
+	c.value = currency
+	return nil
 }
 
 // setCurrencyFlag configures the currency flag for a specific command
-func setCurrencyFlag(f *flag.FlagSet, optional bool) *currencyFlag {
+func setCurrencyFlag(f *flag.FlagSet, registry *currency.Registry, optional bool) *currencyFlag {
 	c := currencyFlag{
 		optional: optional,
+		registry: registry,
 	}
 	description := "Expense currency"
 	f.Var(&c, "currency", description)
@@ -81,31 +83,40 @@ func setCurrencyFlag(f *flag.FlagSet, optional bool) *currencyFlag {
 	return &c
 }
 
-// priceFlag represents the price flag
+// priceFlag represents the price flag, an exact decimal bound to the currency once all the flags are parsed
 type priceFlag struct {
-	value    float64
+	value    decimal
+	set      bool
 	optional bool
 }
 
 func (p priceFlag) String() string {
-	return fmt.Sprintf("%f", p.value)
+	return p.value.String()
 }
 
 func (p *priceFlag) Set(v string) error {
 	if p.optional && v == "" || v == "0" {
 		return nil
 	}
-	price, err := strconv.ParseFloat(v, 64)
+	price, err := parseDecimal(v)
 	if err != nil {
 		return err
 	}
-	if price <= 0 {
+	if price.sign() <= 0 {
 		return errors.New("expense price is required and must be bigger than 0")
 	}
-	p.value = price
+	p.value, p.set = price, true
 	return nil
 }
 
+// money returns the price in a given currency, enforcing the decimal places of the currency
+func (p priceFlag) money(c currency.Currency) (Money, error) {
+	if !p.set {
+		return zeroMoney(c), nil
+	}
+	return p.value.money(c)
+}
+
 // setCurrencyFlag configures the currency flag for a specific command
 func setPriceFlag(f *flag.FlagSet, optional bool) *priceFlag {
 	p := priceFlag{
@@ -150,19 +161,65 @@ func setIDsFlag(f *flag.FlagSet) *idsFlag {
 	return &ids
 }
 
+// idempotencyKeyFlag represents the idempotency-key flag
+type idempotencyKeyFlag struct {
+	value string
+}
+
+func (k idempotencyKeyFlag) String() string {
+	return k.value
+}
+
+func (k *idempotencyKeyFlag) Set(key string) error {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > 255 {
+		return errors.New("idempotency key must not be empty and at most 255 characters long")
+	}
+	for _, c := range key {
+		if c > unicode.MaxASCII || !unicode.IsPrint(c) {
+			return errors.New("idempotency key must contain only printable ascii characters")
+		}
+	}
+	k.value = key
+	return nil
+}
+
+// setIdempotencyKeyFlag configures the idempotency-key flag on a specific command
+func setIdempotencyKeyFlag(f *flag.FlagSet) *idempotencyKeyFlag {
+	var k idempotencyKeyFlag
+	description := "Idempotency key of the operation (generated when omitted), reuse it to safely re-run the command"
+	f.Var(&k, "idempotency-key", description)
+	return &k
+}
+
+// setQuietFlag configures the quiet flag on a specific command
+func setQuietFlag(f *flag.FlagSet) *bool {
+	var quiet bool
+	description := "Print only the id of the expense"
+	f.BoolVar(&quiet, "quiet", false, description)
+	f.BoolVar(&quiet, "q", false, description)
+	return &quiet
+}
+
+const (
+	defaultPage     = 1
+	defaultPageSize = 5
+	maxPageSize     = 25
+)
+
 // pageFlag represents the page flag
 type pageFlag struct {
-	value string
+	value int
 }
 
 func (p pageFlag) String() string {
-	return p.value
+	return strconv.Itoa(p.value)
 }
 
 func (p *pageFlag) Set(page string) error {
 	page = strings.TrimSpace(page)
 	if page == "" {
-		p.value = "1"
+		p.value = defaultPage
 		return nil
 	}
 	pageInt, err := strconv.Atoi(page)
@@ -172,13 +229,13 @@ func (p *pageFlag) Set(page string) error {
 	if pageInt <= 0 {
 		return errors.New("page must be greater than 0")
 	}
-	p.value = page
+	p.value = pageInt
 	return nil
 }
 
 // setPageFlag configures the page flag on a specific command
 func setPageFlag(f *flag.FlagSet) *pageFlag {
-	var p pageFlag
+	p := pageFlag{value: defaultPage}
 	description := "Page number (for pagination)"
 	f.Var(&p, "page", description)
 	f.Var(&p, "p", description)
@@ -187,39 +244,344 @@ func setPageFlag(f *flag.FlagSet) *pageFlag {
 
 // pageSizeFlag represents the page_size flag
 type pageSizeFlag struct {
-	value string
+	value int
+	set   bool
 }
 
 func (ps pageSizeFlag) String() string {
-	return ps.value
+	return strconv.Itoa(ps.value)
 }
 
 func (ps *pageSizeFlag) Set(pageSize string) error {
 	pageSize = strings.TrimSpace(pageSize)
+	ps.set = true
 	if pageSize == "" {
-		ps.value = "5"
+		ps.value = defaultPageSize
 		return nil
 	}
 	pageSizeInt, err := strconv.Atoi(pageSize)
 	if err != nil {
 		return errors.Wrap(err, "invalid page provided")
 	}
-	if pageSizeInt <= 0 || pageSizeInt > 25 {
-		return errors.New("page_size must be greater than 0 and less than 25")
+	if pageSizeInt <= 0 || pageSizeInt > maxPageSize {
+		return fmt.Errorf("page_size must be greater than 0 and less than %d", maxPageSize)
 	}
-	ps.value = pageSize
+	ps.value = pageSizeInt
 	return nil
 }
 
 // setPageSizeFlag configures the page_size flag on a specific command
 func setPageSizeFlag(f *flag.FlagSet) *pageSizeFlag {
-	var ps pageSizeFlag
+	ps := pageSizeFlag{value: defaultPageSize}
 	description := "Page size (for pagination)"
 	f.Var(&ps, "page_size", description)
 	f.Var(&ps, "ps", description)
 	return &ps
 }
 
+// limitFlag represents the limit flag
+type limitFlag struct {
+	value int
+}
+
+func (l limitFlag) String() string {
+	return strconv.Itoa(l.value)
+}
+
+func (l *limitFlag) Set(limit string) error {
+	limitInt, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil {
+		return errors.Wrap(err, "invalid limit provided")
+	}
+	if limitInt < 0 {
+		return errors.New("limit must not be negative")
+	}
+	l.value = limitInt
+	return nil
+}
+
+// setLimitFlag configures the limit flag on a specific command
+func setLimitFlag(f *flag.FlagSet) *limitFlag {
+	var l limitFlag
+	f.Var(&l, "limit", "Maximum number of expenses to fetch, 0 means no limit")
+	return &l
+}
+
+// concurrencyFlag represents the concurrency flag
+type concurrencyFlag struct {
+	value int
+}
+
+func (c concurrencyFlag) String() string {
+	return strconv.Itoa(c.value)
+}
+
+func (c *concurrencyFlag) Set(concurrency string) error {
+	concurrencyInt, err := strconv.Atoi(strings.TrimSpace(concurrency))
+	if err != nil {
+		return errors.Wrap(err, "invalid concurrency provided")
+	}
+	if concurrencyInt <= 0 || concurrencyInt > maxConcurrency {
+		return fmt.Errorf("concurrency must be greater than 0 and at most %d", maxConcurrency)
+	}
+	c.value = concurrencyInt
+	return nil
+}
+
+// setConcurrencyFlag configures the concurrency flag on a specific command
+func setConcurrencyFlag(f *flag.FlagSet, description string) *concurrencyFlag {
+	c := concurrencyFlag{value: defaultConcurrency}
+	f.Var(&c, "concurrency", description)
+	return &c
+}
+
+// dateFlag represents a date filter flag, accepting either a date or an RFC3339 timestamp
+type dateFlag struct {
+	value time.Time
+	// endOfDay moves plain dates to the end of the day, so that the filter includes the whole day
+	endOfDay bool
+}
+
+func (d dateFlag) String() string {
+	if d.value.IsZero() {
+		return ""
+	}
+	return d.value.Format(time.RFC3339)
+}
+
+func (d *dateFlag) Set(date string) error {
+	date = strings.TrimSpace(date)
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		d.value = t
+		return nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return errors.New("date must be formatted as YYYY-MM-DD or RFC3339")
+	}
+	if d.endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	d.value = t
+	return nil
+}
+
+// setFromFlag configures the from flag on a specific command
+func setFromFlag(f *flag.FlagSet) *dateFlag {
+	var d dateFlag
+	f.Var(&d, "from", "Only expenses created at or after this date (YYYY-MM-DD or RFC3339)")
+	return &d
+}
+
+// setToFlag configures the to flag on a specific command
+func setToFlag(f *flag.FlagSet) *dateFlag {
+	d := dateFlag{endOfDay: true}
+	f.Var(&d, "to", "Only expenses created at or before this date (YYYY-MM-DD or RFC3339)")
+	return &d
+}
+
+// priceFilterFlag represents a price range filter flag
+type priceFilterFlag struct {
+	value decimal
+	set   bool
+}
+
+func (p priceFilterFlag) String() string {
+	return p.value.String()
+}
+
+func (p *priceFilterFlag) Set(v string) error {
+	price, err := parseDecimal(v)
+	if err != nil {
+		return errors.Wrap(err, "invalid price provided")
+	}
+	if price.sign() < 0 {
+		return errors.New("price filter must not be negative")
+	}
+	p.value, p.set = price, true
+	return nil
+}
+
+// setPriceRangeFlags configures the min-price and max-price flags on a specific command
+func setPriceRangeFlags(f *flag.FlagSet) (*priceFilterFlag, *priceFilterFlag) {
+	var min, max priceFilterFlag
+	f.Var(&min, "min-price", "Only expenses with a price greater or equal to this price")
+	f.Var(&max, "max-price", "Only expenses with a price less or equal to this price")
+	return &min, &max
+}
+
+// titleContainsFlag represents the title-contains flag
+type titleContainsFlag struct {
+	value string
+}
+
+func (t titleContainsFlag) String() string {
+	return t.value
+}
+
+func (t *titleContainsFlag) Set(title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return errors.New("title-contains must not be empty")
+	}
+	t.value = title
+	return nil
+}
+
+// setTitleContainsFlag configures the title-contains flag on a specific command
+func setTitleContainsFlag(f *flag.FlagSet) *titleContainsFlag {
+	var t titleContainsFlag
+	f.Var(&t, "title-contains", "Only expenses whose title contains this text")
+	return &t
+}
+
+// sortFlag represents the sort flag, a list of fields each optionally prefixed by - for descending order
+type sortFlag struct {
+	value []string
+}
+
+func (s sortFlag) String() string {
+	return strings.Join(s.value, ",")
+}
+
+func (s *sortFlag) Set(sort string) error {
+	fields := []string{"title", "currency", "price", "created_at", "updated_at"}
+	seen := map[string]struct{}{}
+	var value []string
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		name := strings.TrimPrefix(field, "-")
+		valid := false
+		for _, f := range fields {
+			if name == f {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errors.New("sort fields must be one of: " + strings.Join(fields, ",") + ", optionally prefixed by -")
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("sort field '%s' must be provided only once", name)
+		}
+		seen[name] = struct{}{}
+		value = append(value, field)
+	}
+	s.value = value
+	return nil
+}
+
+// setSortFlag configures the sort flag on a specific command
+func setSortFlag(f *flag.FlagSet) *sortFlag {
+	var s sortFlag
+	f.Var(&s, "sort", "Comma separated sort fields, prefixed by - for descending order (e.g. -price,title)")
+	return &s
+}
+
+// expensesFilterFlags represents the flags filtering and sorting the fetched expenses
+type expensesFilterFlags struct {
+	from, to           *dateFlag
+	currency           *currencyFlag
+	minPrice, maxPrice *priceFilterFlag
+	titleContains      *titleContainsFlag
+	sort               *sortFlag
+}
+
+// setExpensesFilterFlags configures the filtering and sorting flags on a specific command
+func setExpensesFilterFlags(f *flag.FlagSet, registry *currency.Registry) expensesFilterFlags {
+	minPrice, maxPrice := setPriceRangeFlags(f)
+	return expensesFilterFlags{
+		from:          setFromFlag(f),
+		to:            setToFlag(f),
+		currency:      setCurrencyFlag(f, registry, true),
+		minPrice:      minPrice,
+		maxPrice:      maxPrice,
+		titleContains: setTitleContainsFlag(f),
+		sort:          setSortFlag(f),
+	}
+}
+
+// apply validates the filter flags against each other and sets them on the query
+func (f expensesFilterFlags) apply(q *ExpensesQuery) error {
+	if !f.from.value.IsZero() && !f.to.value.IsZero() && f.from.value.After(f.to.value) {
+		return errors.New("from must not be after to")
+	}
+	if f.minPrice.set && f.maxPrice.set && f.minPrice.value.cmp(f.maxPrice.value) > 0 {
+		return errors.New("min-price must not be greater than max-price")
+	}
+
+	q.From, q.To = f.from.value, f.to.value
+	q.Currency = f.currency.value
+	if f.minPrice.set {
+		q.MinPrice = f.minPrice.value.String()
+	}
+	if f.maxPrice.set {
+		q.MaxPrice = f.maxPrice.value.String()
+	}
+	q.TitleContains = f.titleContains.value
+	q.Sort = f.sort.value
+	return nil
+}
+
+// setConvertFlags configures the convert-to and rates flags on a specific command
+func setConvertFlags(f *flag.FlagSet, registry *currency.Registry) (*currencyFlag, *string) {
+	to := currencyFlag{optional: true, registry: registry}
+	f.Var(&to, "convert-to", "Currency the prices are converted to, next to the original prices")
+	rates := f.String("rates", "", "Exchange rates file (csv or json) used by convert-to (defaults to rates.csv or rates.json in the config directory)")
+	return &to, rates
+}
+
+// columnsFlag represents the columns flag, selecting and ordering the exported expense fields
+type columnsFlag struct {
+	value []string
+}
+
+func (c columnsFlag) String() string {
+	return strings.Join(c.value, ",")
+}
+
+func (c *columnsFlag) Set(columns string) error {
+	c.value = nil
+	seen := map[string]struct{}{}
+	for _, column := range strings.Split(columns, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column == "" {
+			continue
+		}
+		if !isExpenseColumn(column) {
+			return errors.New("columns must be any of: " + strings.Join(convertedExpenseCSVHeader, ","))
+		}
+		if _, ok := seen[column]; ok {
+			return fmt.Errorf("column '%s' is provided more than once", column)
+		}
+		seen[column] = struct{}{}
+		c.value = append(c.value, column)
+	}
+	if len(c.value) == 0 {
+		return errors.New("at least one column must be provided")
+	}
+	return nil
+}
+
+// converted reports whether any of the columns is a field of the converted price
+func (c columnsFlag) converted() bool {
+	for _, column := range c.value {
+		if isConvertedColumn(column) {
+			return true
+		}
+	}
+	return false
+}
+
+// setColumnsFlag configures the columns flag on a specific command
+func setColumnsFlag(f *flag.FlagSet) *columnsFlag {
+	c := columnsFlag{value: expenseCSVHeader}
+	description := "Comma separated expense fields to export, in order: " + strings.Join(expenseCSVHeader, ",") +
+		" (along with converted_price, converted_currency and rate with convert-to)"
+	f.Var(&c, "columns", description)
+	return &c
+}
+
 // emailFlag represents the email flag
 type emailFlag struct {
 	value string
@@ -277,6 +639,11 @@ func setPasswordFlag(f *flag.FlagSet) *passwordFlag {
 	return &p
 }
 
+// setPasswordStdinFlag configures the password-stdin flag on a specific command
+func setPasswordStdinFlag(f *flag.FlagSet) *bool {
+	return f.Bool("password-stdin", false, "Read the user password from the first line of stdin")
+}
+
 func verifyPwd(pwd string) error {
 	var number, upper, lower, special bool
 	for _, c := range pwd {
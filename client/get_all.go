@@ -1,27 +1,56 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // getAll represents the get-all command which fetches all expenses with pagination
-func (s Switch) getAll() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) getAll() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		getAllCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		page, pageSize := setPageFlag(getAllCmd), setPageSizeFlag(getAllCmd)
+		all := getAllCmd.Bool("all", false, "Fetch every expense, following the pagination starting at --page")
+		limit := setLimitFlag(getAllCmd)
+		concurrency := setConcurrencyFlag(getAllCmd, "Maximum number of pages prefetched concurrently with --all")
+		filters := setExpensesFilterFlags(getAllCmd, s.registry)
+		convertTo, rates := setConvertFlags(getAllCmd, s.registry)
 		if err := s.parseCmd(getAllCmd); err != nil {
 			return err
 		}
+		p, err := s.convertingPrinter(convertTo.value, *rates)
+		if err != nil {
+			return err
+		}
 
-		res, err := s.client.GetAll(page.value, pageSize.value)
+		query := ExpensesQuery{
+			Page:     page.value,
+			PageSize: pageSize.value,
+		}
+		if err := filters.apply(&query); err != nil {
+			return err
+		}
+		if *all {
+			if !pageSize.set {
+				query.PageSize = maxPageSize
+			}
+			it := NewExpensesIterator(ctx, s.client, query, limit.value, concurrency.value)
+			if err := p.printExpensesStream(it); err != nil {
+				return errors.Wrap(err, "could not fetch expenses")
+			}
+			return nil
+		}
+
+		expensesPage, err := s.client.GetAll(ctx, query)
 		if err != nil {
 			return errors.Wrap(err, "could not fetch expenses")
 		}
+		if limit.value > 0 && len(expensesPage.Expenses) > limit.value {
+			expensesPage.Expenses = expensesPage.Expenses[:limit.value]
+		}
 
-		fmt.Printf("expenses fetched successfully:\n%s\n", string(res))
-		return nil
+		return p.printExpensesPage(expensesPage)
 	}
 }
@@ -1,17 +1,18 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // getByIDs represents the get-by-ids command which fetches all expenses by a list of given ids
-func (s Switch) getByIDs() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) getByIDs() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		getByIDsCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		ids := setIDsFlag(getByIDsCmd)
+		convertTo, rates := setConvertFlags(getByIDsCmd, s.registry)
 		if err := s.parseCmd(getByIDsCmd); err != nil {
 			return err
 		}
@@ -21,13 +22,16 @@ func (s Switch) getByIDs() func(string) error {
 		if len(ids.value) == 0 {
 			return errors.New("at least one expense id must be provided")
 		}
+		p, err := s.convertingPrinter(convertTo.value, *rates)
+		if err != nil {
+			return err
+		}
 
-		res, err := s.client.GetByIDs(ids.value...)
+		expenses, err := s.client.GetByIDs(ctx, ids.value...)
 		if err != nil {
 			return errors.Wrap(err, "could not fetch expenses")
 		}
 
-		fmt.Printf("expenses fetched successfully:\n%s\n", string(res))
-		return nil
+		return p.printExpenseList(expenses)
 	}
 }
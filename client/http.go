@@ -2,35 +2,109 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"net/url"
-	"strings"
+	"os"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
 )
 
+// HTTPClientConfig represents the transport configuration of HTTPClient
+type HTTPClientConfig struct {
+	// Timeout limits the time of a single backend call, zero means no timeout
+	Timeout time.Duration
+	// Retry is the policy of retrying failed backend calls
+	Retry RetryPolicy
+	// TLS are the TLS settings used to connect to https backends
+	TLS TLSConfig
+	// Verbosity is the level of HTTP traffic logging written to stderr
+	Verbosity int
+	// Currencies gives the decimal places of the prices returned by the backend, ISO 4217 by default
+	Currencies *currency.Registry
+}
+
 // HTTPClient represents the HTTP client which communicates with reminders backend API
 type HTTPClient struct {
 	client     *http.Client
+	auth       AuthScheme
+	retry      RetryPolicy
+	currencies *currency.Registry
 	BackendURI string
 }
 
 // NewHTTPClient creates a new instance of HTTPClient
-func NewHTTPClient(uri string) HTTPClient {
+func NewHTTPClient(uri string, auth AuthScheme, cfg HTTPClientConfig) (HTTPClient, error) {
+	tlsConfig, err := cfg.TLS.build()
+	if err != nil {
+		return HTTPClient{}, errors.Wrap(err, "could not configure TLS")
+	}
+	if cfg.TLS.insecure() {
+		fmt.Fprintln(os.Stderr, "warning: TLS certificate verification is disabled")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	var roundTripper http.RoundTripper = transport
+	if cfg.Verbosity > VerbosityQuiet {
+		headers := append([]string{}, sensitiveHeaders...)
+		if a, ok := auth.(apiKeyAuth); ok {
+			headers = append(headers, a.header)
+		}
+		roundTripper = loggingTransport{
+			next:             transport,
+			out:              os.Stderr,
+			verbosity:        cfg.Verbosity,
+			sensitiveHeaders: headers,
+		}
+	}
+
+	currencies := cfg.Currencies
+	if currencies == nil {
+		currencies = currency.ISO4217()
+	}
 	return HTTPClient{
 		BackendURI: uri,
-		client:     &http.Client{},
-	}
+		auth:       auth,
+		retry:      cfg.Retry,
+		currencies: currencies,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: roundTripper,
+		},
+	}, nil
 }
 
+// expenseRequestBody represents the body of the create and update requests,
+// the price is sent as an exact json number and an empty price is sent as 0
 type expenseRequestBody struct {
-	Title    string  `json:"title"`
-	Currency string  `json:"currency"`
-	Price    float64 `json:"price"`
+	Title    string      `json:"title"`
+	Currency string      `json:"currency"`
+	Price    json.Number `json:"price"`
+}
+
+type expensesResBody struct {
+	Expenses []expenseJSON `json:"expenses"`
+}
+
+// expensesPageResBody represents the body of the get-all response, see ExpensesPage
+type expensesPageResBody struct {
+	Expenses   []expenseJSON `json:"expenses"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"page_size"`
+	Total      int           `json:"total"`
+	NextPage   int           `json:"next_page"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+type currenciesResBody struct {
+	Currencies []currency.Currency `json:"currencies"`
 }
 
 type authReqBody struct {
@@ -38,128 +112,293 @@ type authReqBody struct {
 	Password string `json:"password"`
 }
 
-// Create calls the create API endpoint
-func (c HTTPClient) Create(title, currency string, price float64) error {
+type refreshReqBody struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Create calls the create API endpoint.
+// The idempotency key is reused on retries, so the expense is created at most once, an empty key is generated
+func (c HTTPClient) Create(ctx context.Context, title string, price Money, idempotencyKey string) (Expense, error) {
 	body := expenseRequestBody{
 		Title:    title,
-		Currency: currency,
-		Price:    price,
+		Currency: price.Currency,
+		Price:    json.Number(price.Decimal()),
 	}
-	req, err := c.newReqWithToken(http.MethodPost, "/expenses", body)
+	req, err := c.newReqWithToken(ctx, createRoute, body)
 	if err != nil {
-		return err
+		return Expense{}, err
 	}
-	_, err = c.apiCall(req, http.StatusCreated)
-	return err
+	setIdempotencyKey(req, idempotencyKey)
+	resBody, err := c.apiCallWithToken(req, createRoute)
+	if err != nil {
+		return Expense{}, err
+	}
+	var res expenseJSON
+	if err := decodeResBody(resBody, &res); err != nil {
+		return Expense{}, err
+	}
+	if res.ID == "" {
+		return Expense{}, errors.New("no expense id found in response body")
+	}
+	return res.expense(c.currencies)
 }
 
-// Update calls the update API endpoint
-func (c HTTPClient) Update(id, title, currency string, price float64) error {
+// Update calls the update API endpoint, empty fields and a zero price amount are not updated.
+// The idempotency key is reused on retries, an empty key is generated
+func (c HTTPClient) Update(ctx context.Context, id, title string, price Money, idempotencyKey string) error {
 	body := expenseRequestBody{
 		Title:    title,
-		Currency: currency,
-		Price:    price,
+		Currency: price.Currency,
 	}
-	req, err := c.newReqWithToken(http.MethodPatch, "/expenses/"+id, body)
+	if price.Amount != 0 {
+		body.Price = json.Number(price.Decimal())
+	}
+	req, err := c.newReqWithToken(ctx, updateRoute, body, id)
 	if err != nil {
 		return err
 	}
-	_, err = c.apiCall(req, http.StatusNoContent)
+	setIdempotencyKey(req, idempotencyKey)
+	_, err = c.apiCallWithToken(req, updateRoute)
 	return err
 }
 
 // Delete calls the delete API endpoint
-func (c HTTPClient) Delete(id string) error {
-	req, err := c.newReqWithToken(http.MethodDelete, "/expenses/"+id, nil)
+func (c HTTPClient) Delete(ctx context.Context, id string) error {
+	req, err := c.newReqWithToken(ctx, deleteRoute, nil, id)
 	if err != nil {
 		return err
 	}
-	_, err = c.apiCall(req, http.StatusNoContent)
+	_, err = c.apiCallWithToken(req, deleteRoute)
 	return err
 }
 
 // GetAll calls the get-all API endpoint
-func (c HTTPClient) GetAll(page, pageSize string) ([]byte, error) {
-	params := url.Values{}
-	params.Add("page", page)
-	params.Add("page_size", pageSize)
-	req, err := c.newReqWithToken(http.MethodPatch, "/expenses?"+params.Encode(), nil)
+func (c HTTPClient) GetAll(ctx context.Context, query ExpensesQuery) (ExpensesPage, error) {
+	req, err := c.newReqWithToken(ctx, getAllRoute, nil)
 	if err != nil {
-		return []byte{}, err
+		return ExpensesPage{}, err
+	}
+	req.URL.RawQuery = query.values().Encode()
+	resBody, err := c.apiCallWithToken(req, getAllRoute)
+	if err != nil {
+		return ExpensesPage{}, err
+	}
+	var res expensesPageResBody
+	if err := decodeResBody(resBody, &res); err != nil {
+		return ExpensesPage{}, err
 	}
-	return c.apiCall(req, http.StatusNoContent)
+	expenses, err := decodeExpenses(res.Expenses, c.currencies)
+	if err != nil {
+		return ExpensesPage{}, err
+	}
+	return ExpensesPage{
+		Expenses:   expenses,
+		Page:       res.Page,
+		PageSize:   res.PageSize,
+		Total:      res.Total,
+		NextPage:   res.NextPage,
+		NextCursor: res.NextCursor,
+	}, nil
 }
 
 // GetByIDs calls the get-by-ids API endpoint
-func (c HTTPClient) GetByIDs(ids ...string) ([]byte, error) {
-	req, err := c.newReqWithToken(http.MethodPatch, "/expenses/"+strings.Join(ids, ","), nil)
+func (c HTTPClient) GetByIDs(ctx context.Context, ids ...string) ([]Expense, error) {
+	req, err := c.newReqWithToken(ctx, getByIDsRoute, nil, ids...)
 	if err != nil {
-		return []byte{}, err
+		return nil, err
+	}
+	resBody, err := c.apiCallWithToken(req, getByIDsRoute)
+	if err != nil {
+		return nil, err
+	}
+	var res expensesResBody
+	if err := decodeResBody(resBody, &res); err != nil {
+		return nil, err
 	}
-	return c.apiCall(req, http.StatusNoContent)
+	return decodeExpenses(res.Expenses, c.currencies)
 }
 
 // Login calls the login API endpoint
-func (c HTTPClient) Login(email, password string) ([]byte, error) {
+func (c HTTPClient) Login(ctx context.Context, email, password string) (Credentials, error) {
 	body := authReqBody{
 		Email:    email,
 		Password: password,
 	}
-	req, err := c.newReq(http.MethodPost, "/login", body)
+	req, err := c.newReq(ctx, loginRoute, body)
 	if err != nil {
-		return []byte{}, err
+		return Credentials{}, err
+	}
+	resBody, err := c.apiCall(req, loginRoute)
+	if err != nil {
+		return Credentials{}, err
 	}
-	return c.apiCall(req, http.StatusOK)
+	return decodeCredentials(resBody)
 }
 
 // Signup calls the signup API endpoint
-func (c HTTPClient) Signup(email, password string) ([]byte, error) {
+func (c HTTPClient) Signup(ctx context.Context, email, password string) (Credentials, error) {
 	body := authReqBody{
 		Email:    email,
 		Password: password,
 	}
-	req, err := c.newReq(http.MethodPost, "/signup", body)
+	req, err := c.newReq(ctx, signupRoute, body)
 	if err != nil {
-		return []byte{}, err
+		return Credentials{}, err
+	}
+	resBody, err := c.apiCall(req, signupRoute)
+	if err != nil {
+		return Credentials{}, err
 	}
-	return c.apiCall(req, http.StatusOK)
+	return decodeCredentials(resBody)
 }
 
-// Logout calls the logout API endpoint
-func (c HTTPClient) Logout() error {
-	req, err := c.newReq(http.MethodPost, "/logout", nil)
+// Logout calls the logout API endpoint with the session to revoke
+func (c HTTPClient) Logout(ctx context.Context) error {
+	req, err := c.newReqWithToken(ctx, logoutRoute, nil)
 	if err != nil {
 		return err
 	}
-	_, err = c.apiCall(req, http.StatusOK)
+	_, err = c.apiCallWithToken(req, logoutRoute)
 	return err
 }
 
+// Me calls the me API endpoint
+func (c HTTPClient) Me(ctx context.Context) (User, error) {
+	req, err := c.newReqWithToken(ctx, meRoute, nil)
+	if err != nil {
+		return User{}, err
+	}
+	resBody, err := c.apiCallWithToken(req, meRoute)
+	if err != nil {
+		return User{}, err
+	}
+	var user User
+	if err := decodeResBody(resBody, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// Currencies calls the currencies API endpoint which lists the currencies accepted by the backend
+func (c HTTPClient) Currencies(ctx context.Context) ([]currency.Currency, error) {
+	req, err := c.newReqWithToken(ctx, currenciesRoute, nil)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := c.apiCallWithToken(req, currenciesRoute)
+	if err != nil {
+		return nil, err
+	}
+	var body currenciesResBody
+	if err := decodeResBody(resBody, &body); err != nil {
+		return nil, err
+	}
+	return body.Currencies, nil
+}
+
+// Refresh calls the refresh API endpoint
+func (c HTTPClient) Refresh(ctx context.Context, refreshToken string) (Credentials, error) {
+	body := refreshReqBody{
+		RefreshToken: refreshToken,
+	}
+	req, err := c.newReq(ctx, refreshRoute, body)
+	if err != nil {
+		return Credentials{}, err
+	}
+	resBody, err := c.apiCall(req, refreshRoute)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return decodeCredentials(resBody)
+}
+
 // apiCall makes a new backend api call
-func (c HTTPClient) apiCall(req *http.Request, resCode int) ([]byte, error) {
-	res, err := c.client.Do(req)
+func (c HTTPClient) apiCall(req *http.Request, rt route) ([]byte, error) {
+	res, resBody, err := c.do(req)
 	if err != nil {
-		e := errors.Wrap(err, "could not make http call")
-		return []byte{}, e
+		return []byte{}, err
 	}
+	return c.checkRes(res, resBody, rt)
+}
 
-	resBody, err := c.readResBody(res.Body)
+// apiCallWithToken makes a new authorized backend api call.
+// If the backend responds with 401 and the auth scheme supports it,
+// the credentials are refreshed and the request is replayed once
+func (c HTTPClient) apiCallWithToken(req *http.Request, rt route) ([]byte, error) {
+	res, resBody, err := c.do(req)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	if res.StatusCode != resCode {
-		if len(resBody) > 0 {
-			fmt.Printf("got this response body:\n%s\n", resBody)
+	r, ok := c.auth.(tokenRefresher)
+	if res.StatusCode == http.StatusUnauthorized && ok {
+		if err := r.refresh(req.Context(), c, req); err != nil {
+			return []byte{}, errors.Wrap(err, "session expired, please log in again")
+		}
+		replayReq, err := c.replayReq(req)
+		if err != nil {
+			return []byte{}, err
+		}
+		res, resBody, err = c.do(replayReq)
+		if err != nil {
+			return []byte{}, err
 		}
-		return []byte{}, fmt.Errorf(
-			"expected response code: %d, got: %d",
-			resCode,
-			res.StatusCode,
-		)
 	}
 
-	return []byte(resBody), err
+	return c.checkRes(res, resBody, rt)
+}
+
+// do sends the request according to the retry policy and reads the response body,
+// the returned response body is already closed
+func (c HTTPClient) do(req *http.Request) (*http.Response, string, error) {
+	attempts := c.retry.attempts(req)
+	for attempt := 1; ; attempt++ {
+		res, resBody, err := c.send(req)
+		if attempt >= attempts || req.Context().Err() != nil {
+			return res, resBody, err
+		}
+		delay, retry := c.retry.delay(attempt, res, err)
+		if !retry {
+			return res, resBody, err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, "", errors.Wrap(req.Context().Err(), "could not make http call")
+		case <-t.C:
+		}
+
+		req, err = cloneReq(req)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// send sends the request once and reads the response body
+func (c HTTPClient) send(req *http.Request) (*http.Response, string, error) {
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "could not make http call")
+	}
+	defer res.Body.Close()
+
+	resBody, err := c.readResBody(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return res, resBody, nil
+}
+
+// checkRes checks the response status code against the route success status codes
+func (c HTTPClient) checkRes(res *http.Response, resBody string, rt route) ([]byte, error) {
+	if !rt.accepts(res.StatusCode) {
+		return []byte{}, newAPIError(res, resBody)
+	}
+
+	return []byte(resBody), nil
 }
 
 // readBody reads response body
@@ -171,35 +410,99 @@ func (c HTTPClient) readResBody(b io.Reader) (string, error) {
 
 	var buff bytes.Buffer
 	if err := json.Indent(&buff, bs, "", "\t"); err != nil {
-		return "", errors.Wrap(err, "could not indent json")
+		// non json bodies (e.g. proxy error pages) are returned as they are
+		return string(bs), nil
 	}
 
 	return buff.String(), nil
 }
 
-func (c HTTPClient) newReq(method, path string, body interface{}) (*http.Request, error) {
-	bs, err := json.Marshal(body)
-	if err != nil {
-		e := errors.Wrap(err, "could not marshal request body")
-		return nil, e
+// decodeCredentials decodes the login/signup response body into Credentials
+func decodeCredentials(resBody []byte) (Credentials, error) {
+	var credentials Credentials
+	if err := decodeResBody(resBody, &credentials); err != nil {
+		return Credentials{}, err
+	}
+	if credentials.AccessToken == "" {
+		return Credentials{}, errors.New("no access token found in response body")
+	}
+	return credentials, nil
+}
+
+// decodeResBody decodes a json response body into v
+func decodeResBody(resBody []byte, v interface{}) error {
+	if len(resBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resBody, v); err != nil {
+		return errors.Wrap(err, "could not decode json response body")
+	}
+	return nil
+}
+
+// newReq creates a new request for a given route, the params fill in the route path placeholders
+func (c HTTPClient) newReq(ctx context.Context, rt route, body interface{}, params ...string) (*http.Request, error) {
+	var reqBody io.Reader
+	if body != nil {
+		bs, err := json.Marshal(body)
+		if err != nil {
+			e := errors.Wrap(err, "could not marshal request body")
+			return nil, e
+		}
+		reqBody = bytes.NewReader(bs)
 	}
-	req, err := http.NewRequest(method, c.BackendURI+path, bytes.NewReader(bs))
+	req, err := http.NewRequestWithContext(ctx, rt.method, c.BackendURI+rt.url(params...), reqBody)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create http request")
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
 	return req, nil
 }
 
-func (c HTTPClient) newReqWithToken(method, path string, body interface{}) (*http.Request, error) {
-	req, err := c.newReq(method, path, body)
+// newReqWithToken creates a new authorized request for a given route
+func (c HTTPClient) newReqWithToken(ctx context.Context, rt route, body interface{}, params ...string) (*http.Request, error) {
+	req, err := c.newReq(ctx, rt, body, params...)
 	if err != nil {
 		return nil, err
 	}
-	credentials, err := readCredentials()
-	if err != nil {
-		return nil, errors.Wrap(err, "could not read credentials")
+	if err := c.auth.Authorize(req); err != nil {
+		return nil, errors.Wrap(err, "could not authorize request")
 	}
-	req.Header.Add("Bearer", credentials.AccessToken)
 	return req, nil
 }
+
+// replayReq clones an authorized request with a fresh body and authorizes it again
+func (c HTTPClient) replayReq(req *http.Request) (*http.Request, error) {
+	replayReq, err := cloneReq(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.auth.Authorize(replayReq); err != nil {
+		return nil, errors.Wrap(err, "could not authorize request")
+	}
+	return replayReq, nil
+}
+
+// cloneReq clones a request with a fresh body, so that it can be sent again
+func cloneReq(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not replay request body")
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// setIdempotencyKey sets the idempotency key header of a request, generating a new key if none is given
+func setIdempotencyKey(req *http.Request, key string) {
+	if key == "" {
+		key = uuid.New().String()
+	}
+	req.Header.Set(idempotencyKeyHeader, key)
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sentRequest represents the parts of a request received by the test backend
+type sentRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+func TestHTTPClientRoutes(t *testing.T) {
+	setConfigHome(t)
+	if err := saveCredentials(DefaultProfileName, Credentials{AccessToken: "token"}); err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		call       func(ctx context.Context, c HTTPClient) error
+		status     int
+		resBody    string
+		wantMethod string
+		wantPath   string
+		wantQuery  string
+		wantHeader map[string]string
+		wantBody   string
+	}{
+		{
+			name: "get all",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.GetAll(ctx, ExpensesQuery{
+					Page:          2,
+					PageSize:      10,
+					From:          from,
+					Currency:      "USD",
+					MinPrice:      "1.50",
+					TitleContains: "a b",
+					Sort:          []string{"-price", "title"},
+				})
+				return err
+			},
+			status:     http.StatusOK,
+			resBody:    `{"expenses":[],"page":2,"page_size":10,"total":0}`,
+			wantMethod: http.MethodGet,
+			wantPath:   "/expenses",
+			wantQuery:  "currency=USD&from=2020-01-02T00%3A00%3A00Z&min_price=1.50&page=2&page_size=10&sort=-price%2Ctitle&title_contains=a+b",
+			wantHeader: map[string]string{"Authorization": "Bearer token", "Accept": "application/json", "Content-Type": ""},
+		},
+		{
+			name: "get by ids",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.GetByIDs(ctx, "a/1", "b")
+				return err
+			},
+			status:     http.StatusOK,
+			resBody:    `{"expenses":[]}`,
+			wantMethod: http.MethodGet,
+			wantPath:   "/expenses/a%2F1,b",
+			wantHeader: map[string]string{"Authorization": "Bearer token", "Accept": "application/json"},
+		},
+		{
+			name: "create",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.Create(ctx, "lunch", testMoney(1999, "USD"), "key-1")
+				return err
+			},
+			status:     http.StatusCreated,
+			resBody:    `{"id":"e1","title":"lunch","currency":"USD","price":19.99}`,
+			wantMethod: http.MethodPost,
+			wantPath:   "/expenses",
+			wantHeader: map[string]string{
+				"Authorization":   "Bearer token",
+				"Content-Type":    "application/json",
+				"Idempotency-Key": "key-1",
+			},
+			wantBody: `{"title":"lunch","currency":"USD","price":19.99}`,
+		},
+		{
+			name: "update",
+			call: func(ctx context.Context, c HTTPClient) error {
+				return c.Update(ctx, "e1", "", testMoney(500, "EUR"), "key-2")
+			},
+			status:     http.StatusNoContent,
+			wantMethod: http.MethodPatch,
+			wantPath:   "/expenses/e1",
+			wantHeader: map[string]string{
+				"Authorization":   "Bearer token",
+				"Content-Type":    "application/json",
+				"Idempotency-Key": "key-2",
+			},
+			wantBody: `{"title":"","currency":"EUR","price":5.00}`,
+		},
+		{
+			name: "delete",
+			call: func(ctx context.Context, c HTTPClient) error {
+				return c.Delete(ctx, "e1")
+			},
+			status:     http.StatusNoContent,
+			wantMethod: http.MethodDelete,
+			wantPath:   "/expenses/e1",
+			wantHeader: map[string]string{"Authorization": "Bearer token", "Content-Type": ""},
+		},
+		{
+			name: "login",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.Login(ctx, "a@b.co", "secret")
+				return err
+			},
+			status:     http.StatusOK,
+			resBody:    `{"access_token":"token"}`,
+			wantMethod: http.MethodPost,
+			wantPath:   "/login",
+			wantHeader: map[string]string{"Authorization": "", "Content-Type": "application/json"},
+			wantBody:   `{"email":"a@b.co","password":"secret"}`,
+		},
+		{
+			name: "signup",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.Signup(ctx, "a@b.co", "secret")
+				return err
+			},
+			status:     http.StatusCreated,
+			resBody:    `{"access_token":"token"}`,
+			wantMethod: http.MethodPost,
+			wantPath:   "/signup",
+			wantHeader: map[string]string{"Authorization": "", "Content-Type": "application/json"},
+			wantBody:   `{"email":"a@b.co","password":"secret"}`,
+		},
+		{
+			name: "logout",
+			call: func(ctx context.Context, c HTTPClient) error {
+				return c.Logout(ctx)
+			},
+			status:     http.StatusNoContent,
+			wantMethod: http.MethodPost,
+			wantPath:   "/logout",
+			wantHeader: map[string]string{"Authorization": "Bearer token", "Content-Type": ""},
+		},
+		{
+			name: "refresh",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.Refresh(ctx, "refresh")
+				return err
+			},
+			status:     http.StatusOK,
+			resBody:    `{"access_token":"token"}`,
+			wantMethod: http.MethodPost,
+			wantPath:   "/refresh",
+			wantHeader: map[string]string{"Authorization": "", "Content-Type": "application/json"},
+			wantBody:   `{"refresh_token":"refresh"}`,
+		},
+		{
+			name: "me",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.Me(ctx)
+				return err
+			},
+			status:     http.StatusOK,
+			resBody:    `{"id":"u1","email":"a@b.co"}`,
+			wantMethod: http.MethodGet,
+			wantPath:   "/me",
+			wantHeader: map[string]string{"Authorization": "Bearer token"},
+		},
+		{
+			name: "currencies",
+			call: func(ctx context.Context, c HTTPClient) error {
+				_, err := c.Currencies(ctx)
+				return err
+			},
+			status:     http.StatusOK,
+			resBody:    `{"currencies":[{"code":"USD","minor_units":2,"name":"US Dollar"}]}`,
+			wantMethod: http.MethodGet,
+			wantPath:   "/currencies",
+			wantHeader: map[string]string{"Authorization": "Bearer token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sentRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bs, _ := ioutil.ReadAll(r.Body)
+				got = append(got, sentRequest{
+					method: r.Method,
+					path:   r.URL.EscapedPath(),
+					query:  r.URL.RawQuery,
+					header: r.Header,
+					body:   string(bs),
+				})
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.resBody))
+			}))
+			defer srv.Close()
+
+			auth, err := NewAuthScheme(DefaultProfileName, AuthConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := NewHTTPClient(srv.URL, auth, HTTPClientConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.call(context.Background(), c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("got %d requests, want 1", len(got))
+			}
+			req := got[0]
+			if req.method != tt.wantMethod {
+				t.Errorf("got method %s, want %s", req.method, tt.wantMethod)
+			}
+			if req.path != tt.wantPath {
+				t.Errorf("got path %s, want %s", req.path, tt.wantPath)
+			}
+			if req.query != tt.wantQuery {
+				t.Errorf("got query %s, want %s", req.query, tt.wantQuery)
+			}
+			for name, want := range tt.wantHeader {
+				if v := req.header.Get(name); v != want {
+					t.Errorf("got header %s: %q, want %q", name, v, want)
+				}
+			}
+			if req.body != tt.wantBody {
+				t.Errorf("got body %s, want %s", req.body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHTTPClientUnexpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message":"invalid title"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewHTTPClient(srv.URL, apiKeyAuth{header: defaultAPIKeyHeader, key: "key"}, HTTPClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Login(context.Background(), "a@b.co", "secret")
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("got error %v, want an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status code %d, want %d", apiErr.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPClientCreateWithoutID(t *testing.T) {
+	tests := []struct {
+		name    string
+		resBody string
+	}{
+		{name: "empty body", resBody: ""},
+		{name: "empty object", resBody: "{}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(tt.resBody))
+			}))
+			defer srv.Close()
+
+			c, err := NewHTTPClient(srv.URL, apiKeyAuth{header: defaultAPIKeyHeader, key: "key"}, HTTPClientConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Create(context.Background(), "lunch", testMoney(1999, "USD"), ""); err == nil {
+				t.Error("got no error, want an error for a created expense without id")
+			}
+		})
+	}
+}
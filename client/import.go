@@ -0,0 +1,383 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	csvImportFormat    = "csv"
+	jsonImportFormat   = "json"
+	ndjsonImportFormat = "ndjson"
+)
+
+var importFields = []string{"title", "currency", "price"}
+
+// importRow represents a single row of an import file before validation, rows are numbered from 1
+type importRow struct {
+	row      int
+	title    string
+	currency string
+	price    string
+}
+
+// importResult represents the outcome of importing a single row
+type importResult struct {
+	row    importRow
+	id     string
+	status string
+	reason string
+}
+
+// importSummary represents the summary printed at the end of an import
+type importSummary struct {
+	Created int    `json:"created"`
+	Skipped int    `json:"skipped"`
+	Failed  int    `json:"failed"`
+	Rejects string `json:"rejects,omitempty"`
+}
+
+// importCmd represents the import command which creates expenses in bulk from a csv, json or ndjson file.
+// Every row is validated like the create flags, invalid rows are skipped and every rejected row
+// is written to the rejects file along with the reason
+func (s Switch) importCmd() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
+		importCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
+		file := importCmd.String("file", "", "File to import expenses from, or - for stdin")
+		format := importCmd.String("format", "", "Format of the file: csv, json or ndjson (detected from the file extension by default)")
+		mapping := importCmd.String("map", "", "Csv column of every field, by header name or 1-based index, e.g. title=Description,price=Amount")
+		delimiter := importCmd.String("delimiter", ",", "Csv field delimiter")
+		noHeader := importCmd.Bool("no-header", false, "The csv file has no header row, every field must be mapped by index with --map, e.g. title=2,currency=3,price=4")
+		rejects := importCmd.String("rejects", "", "File the rejected rows are written to (defaults to <file>.rejects.csv)")
+		concurrency := setConcurrencyFlag(importCmd, "Maximum number of expenses created concurrently")
+
+		if err := s.parseCmd(importCmd); err != nil {
+			return err
+		}
+		if err := s.checkArgs(importCmd, 1); err != nil {
+			return err
+		}
+		if *file == "" {
+			return errors.New("file to import must be provided")
+		}
+		f, err := importFormat(*file, *format)
+		if err != nil {
+			return err
+		}
+		if *rejects == "" {
+			*rejects = rejectsPath(*file)
+		}
+
+		var r io.Reader = os.Stdin
+		if *file != "-" {
+			in, err := os.Open(*file)
+			if err != nil {
+				return errors.Wrap(err, "could not open import file")
+			}
+			defer in.Close()
+			r = in
+		}
+
+		var rows []importRow
+		switch f {
+		case csvImportFormat:
+			rows, err = readCSVRows(r, *mapping, *delimiter, !*noHeader)
+		default:
+			rows, err = readJSONRows(r, f)
+		}
+		if err != nil {
+			return err
+		}
+
+		results := make([]importResult, len(rows))
+		forEachConcurrently(ctx, len(rows), concurrency.value, func(i int) {
+			results[i] = s.importRow(ctx, rows[i])
+		})
+
+		var summary importSummary
+		var rejected []importResult
+		for i, res := range results {
+			if res.status == "" {
+				res = importResult{row: rows[i], status: "failed", reason: fmt.Sprintf("not imported: %v", ctx.Err())}
+			}
+			switch res.status {
+			case "created":
+				summary.Created++
+				continue
+			case "skipped":
+				summary.Skipped++
+			default:
+				summary.Failed++
+			}
+			rejected = append(rejected, res)
+		}
+		if len(rejected) > 0 {
+			if err := writeRejects(*rejects, rejected); err != nil {
+				return err
+			}
+			summary.Rejects = *rejects
+		}
+
+		if err := s.printImportSummary(summary); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "could not import expenses")
+		}
+		if len(rejected) > 0 {
+			return fmt.Errorf("%d of %d row(s) could not be imported, see %s", len(rejected), len(rows), *rejects)
+		}
+		return nil
+	}
+}
+
+// importRow validates a row and creates the expense, invalid rows are skipped
+func (s Switch) importRow(ctx context.Context, row importRow) importResult {
+	t, c, p := titleFlag{}, currencyFlag{registry: s.registry}, priceFlag{}
+	if err := t.Set(row.title); err != nil {
+		return importResult{row: row, status: "skipped", reason: err.Error()}
+	}
+	if err := c.Set(row.currency); err != nil {
+		return importResult{row: row, status: "skipped", reason: err.Error()}
+	}
+	if err := p.Set(row.price); err != nil {
+		return importResult{row: row, status: "skipped", reason: "invalid price: " + err.Error()}
+	}
+	if !p.set {
+		return importResult{row: row, status: "skipped", reason: "expense price is required and must be bigger than 0"}
+	}
+	price, err := p.money(knownCurrency(s.knownCurrencies, c.value))
+	if err != nil {
+		return importResult{row: row, status: "skipped", reason: "invalid price: " + err.Error()}
+	}
+
+	expense, err := s.client.Create(ctx, t.value, price, "")
+	if err != nil {
+		return importResult{row: row, status: "failed", reason: err.Error()}
+	}
+	return importResult{row: row, id: expense.ID, status: "created"}
+}
+
+// printImportSummary prints the import summary, the table format prints a human friendly line
+func (s Switch) printImportSummary(summary importSummary) error {
+	switch s.printer.format {
+	case TableOutput:
+		fmt.Fprintf(s.printer.out, "created: %d, skipped: %d, failed: %d\n", summary.Created, summary.Skipped, summary.Failed)
+		if summary.Rejects != "" {
+			fmt.Fprintf(s.printer.out, "rejected rows written to: %s\n", summary.Rejects)
+		}
+		return nil
+	case CSVOutput:
+		w := csv.NewWriter(s.printer.out)
+		_ = w.Write([]string{"created", "skipped", "failed", "rejects"})
+		_ = w.Write([]string{
+			strconv.Itoa(summary.Created),
+			strconv.Itoa(summary.Skipped),
+			strconv.Itoa(summary.Failed),
+			summary.Rejects,
+		})
+		w.Flush()
+		return errors.Wrap(w.Error(), "could not write csv")
+	default:
+		return s.printer.printValue(summary)
+	}
+}
+
+// importFormat returns the format of the import file, detecting it from the file extension when not provided
+func importFormat(file, format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".csv":
+			format = csvImportFormat
+		case ".json":
+			format = jsonImportFormat
+		case ".ndjson", ".jsonl":
+			format = ndjsonImportFormat
+		default:
+			return "", errors.New("format of the file must be provided with --format")
+		}
+	}
+	switch format {
+	case csvImportFormat, jsonImportFormat, ndjsonImportFormat:
+		return format, nil
+	default:
+		return "", errors.New("format must be one of: csv,json,ndjson")
+	}
+}
+
+// rejectsPath returns the default rejects file of an import file
+func rejectsPath(file string) string {
+	if file == "-" {
+		return "import.rejects.csv"
+	}
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ".rejects.csv"
+}
+
+// readCSVRows reads the rows of a csv file, mapping the columns to the expense fields.
+// Without a header row, such as in some bank exports, the columns are only mapped by index
+func readCSVRows(r io.Reader, mapping, delimiter string, hasHeader bool) ([]importRow, error) {
+	if len([]rune(delimiter)) != 1 {
+		return nil, errors.New("csv delimiter must be a single character")
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = []rune(delimiter)[0]
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var header []string
+	if hasHeader {
+		h, err := cr.Read()
+		if err == io.EOF {
+			return nil, errors.New("csv file must start with a header, use --no-header for files without one")
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read csv header")
+		}
+		header = h
+	}
+	columns, err := csvColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read csv")
+		}
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		rows = append(rows, importRow{
+			row:      len(rows) + 1,
+			title:    field("title"),
+			currency: field("currency"),
+			price:    field("price"),
+		})
+	}
+}
+
+// csvColumns finds the column index of every expense field. Fields which are not mapped
+// are looked up in the header by their own name, a nil header only allows index mappings
+func csvColumns(header []string, mapping string) (map[string]int, error) {
+	names := map[string]string{}
+	for _, field := range importFields {
+		names[field] = field
+	}
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		field := strings.ToLower(strings.TrimSpace(kv[0]))
+		if _, ok := names[field]; !ok || len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			return nil, errors.Errorf("invalid column mapping '%s', expected <%s>=<column>", pair, strings.Join(importFields, "|"))
+		}
+		names[field] = strings.TrimSpace(kv[1])
+	}
+
+	columns := map[string]int{}
+	for field, name := range names {
+		if i, err := strconv.Atoi(name); err == nil {
+			if i < 1 || (header != nil && i > len(header)) {
+				return nil, errors.Errorf("column %d of the %s field is out of range", i, field)
+			}
+			columns[field] = i - 1
+			continue
+		}
+		if header == nil {
+			return nil, errors.Errorf("csv file has no header, the %s field must be mapped by column index", field)
+		}
+		found := false
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				columns[field], found = i, true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("csv header has no '%s' column for the %s field", name, field)
+		}
+	}
+	return columns, nil
+}
+
+// readJSONRows reads the rows of a json array, or of newline delimited json objects
+func readJSONRows(r io.Reader, format string) ([]importRow, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if format == jsonImportFormat {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode json")
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("json file must contain an array of expenses")
+		}
+	}
+
+	var rows []importRow
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			return nil, errors.Wrapf(err, "could not decode json row %d", len(rows)+1)
+		}
+		field := func(name string) string {
+			if v, ok := record[name]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+		rows = append(rows, importRow{
+			row:      len(rows) + 1,
+			title:    field("title"),
+			currency: field("currency"),
+			price:    field("price"),
+		})
+	}
+	if format == jsonImportFormat {
+		if _, err := dec.Token(); err != nil {
+			return nil, errors.Wrap(err, "could not decode json")
+		}
+	}
+	return rows, nil
+}
+
+// writeRejects writes the rejected rows along with the reason to a csv file
+func writeRejects(path string, rejected []importResult) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"row", "title", "currency", "price", "status", "reason"})
+	for _, r := range rejected {
+		_ = w.Write([]string{strconv.Itoa(r.row.row), r.row.title, r.row.currency, r.row.price, r.status, r.reason})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, "could not write rejects")
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return errors.Wrap(err, "could not write rejects file")
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+func TestReadCSVRows(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		mapping   string
+		delimiter string
+		noHeader  bool
+		want      []importRow
+		wantErr   bool
+	}{
+		{
+			name:  "fields by their own name",
+			input: "price,title,currency\n19.99,Lunch,EUR\n5,Coffee,USD\n",
+			want: []importRow{
+				{row: 1, title: "Lunch", currency: "EUR", price: "19.99"},
+				{row: 2, title: "Coffee", currency: "USD", price: "5"},
+			},
+		},
+		{
+			name:    "mapping by header name",
+			input:   "Date,Description,Amount,Ccy\n2020-01-20,Lunch,19.99,EUR\n",
+			mapping: "title=description, price=AMOUNT,currency=Ccy",
+			want:    []importRow{{row: 1, title: "Lunch", currency: "EUR", price: "19.99"}},
+		},
+		{
+			name:    "mapping by index",
+			input:   "Date,Description,Amount,Ccy\n2020-01-20,Lunch,19.99,EUR\n",
+			mapping: "title=2,price=3,currency=4",
+			want:    []importRow{{row: 1, title: "Lunch", currency: "EUR", price: "19.99"}},
+		},
+		{
+			name:      "delimiter and quoted fields",
+			input:     "title;currency;price\n\"Lunch; with friends\";EUR;\"1,234.50\"\n",
+			delimiter: ";",
+			want:      []importRow{{row: 1, title: "Lunch; with friends", currency: "EUR", price: "1,234.50"}},
+		},
+		{
+			name:  "short rows leave the missing fields empty",
+			input: "title,currency,price\nLunch,EUR\n",
+			want:  []importRow{{row: 1, title: "Lunch", currency: "EUR"}},
+		},
+		{
+			name:     "no header mapped by index",
+			input:    "2020-01-20,Lunch,19.99,EUR\n2020-01-21,Coffee,2.50,EUR\n",
+			mapping:  "title=2,price=3,currency=4",
+			noHeader: true,
+			want: []importRow{
+				{row: 1, title: "Lunch", currency: "EUR", price: "19.99"},
+				{row: 2, title: "Coffee", currency: "EUR", price: "2.50"},
+			},
+		},
+		{name: "no header mapped by name", input: "Lunch,EUR,19.99\n", mapping: "title=title,currency=2,price=3", noHeader: true, wantErr: true},
+		{name: "no header without mapping", input: "Lunch,EUR,19.99\n", noHeader: true, wantErr: true},
+		{name: "missing column", input: "title,price\nLunch,19.99\n", wantErr: true},
+		{name: "index out of range", input: "title,currency,price\nLunch,EUR,19.99\n", mapping: "price=4", wantErr: true},
+		{name: "zero index", input: "title,currency,price\nLunch,EUR,19.99\n", mapping: "price=0", wantErr: true},
+		{name: "unknown field", input: "title,currency,price\nLunch,EUR,19.99\n", mapping: "amount=3", wantErr: true},
+		{name: "mapping without column", input: "title,currency,price\nLunch,EUR,19.99\n", mapping: "price=", wantErr: true},
+		{name: "empty file", input: "", wantErr: true},
+		{name: "invalid delimiter", input: "title,currency,price\n", delimiter: ";;", wantErr: true},
+		{name: "malformed quotes", input: "title,currency,price\n\"Lunch,EUR,19.99\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delimiter := tt.delimiter
+			if delimiter == "" {
+				delimiter = ","
+			}
+			got, err := readCSVRows(strings.NewReader(tt.input), tt.mapping, delimiter, !tt.noHeader)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got rows %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadJSONRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		want    []importRow
+		wantErr bool
+	}{
+		{
+			name:   "json array",
+			input:  `[{"title":"Lunch","currency":"EUR","price":19.99},{"title":"Coffee","currency":"USD","price":"2.50"}]`,
+			format: jsonImportFormat,
+			want: []importRow{
+				{row: 1, title: "Lunch", currency: "EUR", price: "19.99"},
+				{row: 2, title: "Coffee", currency: "USD", price: "2.50"},
+			},
+		},
+		{
+			name:   "numbers are kept as written",
+			input:  `[{"title":"Big","currency":"JPY","price":12345678901234567}]`,
+			format: jsonImportFormat,
+			want:   []importRow{{row: 1, title: "Big", currency: "JPY", price: "12345678901234567"}},
+		},
+		{
+			name:   "ndjson with null and missing fields",
+			input:  "{\"title\":\"Lunch\",\"currency\":\"EUR\",\"price\":19.99}\n{\"title\":null,\"price\":5}\n",
+			format: ndjsonImportFormat,
+			want: []importRow{
+				{row: 1, title: "Lunch", currency: "EUR", price: "19.99"},
+				{row: 2, price: "5"},
+			},
+		},
+		{name: "empty array", input: `[]`, format: jsonImportFormat},
+		{name: "object instead of array", input: `{"title":"Lunch"}`, format: jsonImportFormat, wantErr: true},
+		{name: "bad row", input: `[{"title":"Lunch"},"oops"]`, format: jsonImportFormat, wantErr: true},
+		{name: "unterminated array", input: `[{"title":"Lunch"}`, format: jsonImportFormat, wantErr: true},
+		{name: "bad ndjson line", input: "{\"title\":\"Lunch\"}\n{oops}\n", format: ndjsonImportFormat, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readJSONRows(strings.NewReader(tt.input), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got rows %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportRowsRejects(t *testing.T) {
+	backend := &fakeBackend{create: func(title string, price Money) (Expense, error) {
+		if title == "Conflict" {
+			return Expense{}, errors.New("backend down")
+		}
+		return Expense{ID: "id-" + title, Title: title, Price: price}, nil
+	}}
+	s := Switch{client: backend, registry: currency.ISO4217(), knownCurrencies: currency.ISO4217()}
+	rows, err := readCSVRows(strings.NewReader(`title,currency,price
+Lunch,EUR,19.99
+,EUR,5
+Coffee,XXX,5
+Tea,EUR,abc
+Free,EUR,0
+Yen,JPY,1.5
+Conflict,EUR,5
+`), "", ",", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rejected []importResult
+	for _, row := range rows {
+		if res := s.importRow(context.Background(), row); res.status != "created" {
+			rejected = append(rejected, res)
+		} else if res.id != "id-Lunch" {
+			t.Errorf("got created id %s, want id-Lunch", res.id)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "import.rejects.csv")
+	if err := writeRejects(path, rejected); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"row", "title", "currency", "price", "status"},
+		{"2", "", "EUR", "5", "skipped"},
+		{"3", "Coffee", "XXX", "5", "skipped"},
+		{"4", "Tea", "EUR", "abc", "skipped"},
+		{"5", "Free", "EUR", "0", "skipped"},
+		{"6", "Yen", "JPY", "1.5", "skipped"},
+		{"7", "Conflict", "EUR", "5", "failed"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d rejects rows, want %d: %v", len(records), len(want), records)
+	}
+	for i, record := range records {
+		if !reflect.DeepEqual(record[:5], want[i]) {
+			t.Errorf("got rejects row %v, want %v", record[:5], want[i])
+		}
+		if i > 0 && record[5] == "" {
+			t.Errorf("got rejects row %v without a reason", record)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	defaultConcurrency = 4
+	maxConcurrency     = 16
+)
+
+// pageResult represents the outcome of fetching a single page
+type pageResult struct {
+	page ExpensesPage
+	err  error
+}
+
+// ExpensesIterator iterates over every expense by following the backend pagination metadata.
+// Pages are prefetched in the background, at most concurrency at a time, and delivered in order
+type ExpensesIterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	client BackendHTTPClient
+	query  ExpensesQuery
+	limit  int
+
+	once     sync.Once
+	futures  chan chan pageResult
+	current  []Expense
+	expense  Expense
+	returned int
+	err      error
+}
+
+// NewExpensesIterator creates a new instance of ExpensesIterator starting at the query page,
+// limit stops the iteration after that many expenses, 0 means no limit
+func NewExpensesIterator(ctx context.Context, client BackendHTTPClient, query ExpensesQuery, limit, concurrency int) *ExpensesIterator {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if query.Page <= 0 && query.Cursor == "" {
+		query.Page = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &ExpensesIterator{
+		ctx:     ctx,
+		cancel:  cancel,
+		client:  client,
+		query:   query,
+		limit:   limit,
+		futures: make(chan chan pageResult, concurrency-1),
+	}
+}
+
+// Next advances the iterator to the next expense and reports whether there is one
+func (it *ExpensesIterator) Next() bool {
+	it.once.Do(func() {
+		go it.prefetch()
+	})
+	if it.err != nil || (it.limit > 0 && it.returned >= it.limit) {
+		it.Close()
+		return false
+	}
+
+	for len(it.current) == 0 {
+		future, ok := <-it.futures
+		if !ok {
+			it.Close()
+			return false
+		}
+		res := <-future
+		if res.err != nil {
+			it.err = res.err
+			it.Close()
+			return false
+		}
+		it.current = res.page.Expenses
+	}
+
+	it.expense, it.current = it.current[0], it.current[1:]
+	it.returned++
+	return true
+}
+
+// Expense returns the current expense
+func (it *ExpensesIterator) Expense() Expense {
+	return it.expense
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *ExpensesIterator) Err() error {
+	return it.err
+}
+
+// Close stops prefetching the remaining pages
+func (it *ExpensesIterator) Close() {
+	it.cancel()
+}
+
+// prefetch fetches the pages in the background and queues their futures in order.
+// When the backend reports the total, the remaining pages are fetched concurrently,
+// otherwise next_page, next_cursor or a full page are followed one page at a time
+func (it *ExpensesIterator) prefetch() {
+	defer close(it.futures)
+
+	res := it.fetchNow(it.query)
+	if !it.enqueue(resolved(res)) || res.err != nil {
+		return
+	}
+
+	// the following pages are computed from the requested page, as backends may not echo it back
+	page := res.page
+	if it.query.Cursor == "" && page.NextCursor == "" && page.NextPage == 0 && page.Total > 0 && page.PageSize > 0 {
+		lastPage := (page.Total + page.PageSize - 1) / page.PageSize
+		for p := it.query.Page + 1; p <= lastPage; p++ {
+			q := it.query
+			q.Page, q.PageSize = p, page.PageSize
+			if !it.enqueueFetch(q) {
+				return
+			}
+		}
+		return
+	}
+
+	q := it.query
+	for {
+		switch {
+		case page.NextCursor != "":
+			q.Cursor, q.Page = page.NextCursor, 0
+		case page.NextPage > 0:
+			q.Page = page.NextPage
+		case q.Cursor == "" && page.Total == 0 && page.PageSize > 0 && len(page.Expenses) >= page.PageSize:
+			q.Page++
+		default:
+			return
+		}
+		res := it.fetchNow(q)
+		if !it.enqueue(resolved(res)) || res.err != nil || len(res.page.Expenses) == 0 {
+			return
+		}
+		page = res.page
+	}
+}
+
+// fetchNow fetches a page synchronously
+func (it *ExpensesIterator) fetchNow(q ExpensesQuery) pageResult {
+	page, err := it.client.GetAll(it.ctx, q)
+	return pageResult{page: page, err: err}
+}
+
+// enqueueFetch queues the future of a page and starts fetching it,
+// so that at most concurrency pages are fetched or waiting to be consumed at any time
+func (it *ExpensesIterator) enqueueFetch(q ExpensesQuery) bool {
+	future := make(chan pageResult, 1)
+	if !it.enqueue(future) {
+		return false
+	}
+	go func() {
+		future <- it.fetchNow(q)
+	}()
+	return true
+}
+
+// enqueue queues a future, blocking while the queue is full
+func (it *ExpensesIterator) enqueue(future chan pageResult) bool {
+	select {
+	case it.futures <- future:
+		return true
+	case <-it.ctx.Done():
+		return false
+	}
+}
+
+// resolved returns a future which is already resolved with a given result
+func resolved(res pageResult) chan pageResult {
+	future := make(chan pageResult, 1)
+	future <- res
+	return future
+}
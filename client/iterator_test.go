@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+// fakeBackend serves the get-all and create calls with funcs and records the queries,
+// the currencies call returns the given currencies and the other calls are not used
+type fakeBackend struct {
+	getAll     func(q ExpensesQuery) (ExpensesPage, error)
+	create     func(title string, price Money) (Expense, error)
+	currencies []currency.Currency
+
+	mu      sync.Mutex
+	queries []ExpensesQuery
+}
+
+func (b *fakeBackend) GetAll(_ context.Context, q ExpensesQuery) (ExpensesPage, error) {
+	b.mu.Lock()
+	b.queries = append(b.queries, q)
+	b.mu.Unlock()
+	return b.getAll(q)
+}
+
+func (b *fakeBackend) GetByIDs(context.Context, ...string) ([]Expense, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (b *fakeBackend) Create(_ context.Context, title string, price Money, _ string) (Expense, error) {
+	if b.create == nil {
+		return Expense{}, errors.New("not implemented")
+	}
+	return b.create(title, price)
+}
+
+func (b *fakeBackend) Update(context.Context, string, string, Money, string) error {
+	return errors.New("not implemented")
+}
+
+func (b *fakeBackend) Delete(context.Context, string) error {
+	return errors.New("not implemented")
+}
+
+func (b *fakeBackend) Login(context.Context, string, string) (Credentials, error) {
+	return Credentials{}, errors.New("not implemented")
+}
+
+func (b *fakeBackend) Logout(context.Context) error {
+	return errors.New("not implemented")
+}
+
+func (b *fakeBackend) Signup(context.Context, string, string) (Credentials, error) {
+	return Credentials{}, errors.New("not implemented")
+}
+
+func (b *fakeBackend) Me(context.Context) (User, error) {
+	return User{}, errors.New("not implemented")
+}
+
+func (b *fakeBackend) Currencies(context.Context) ([]currency.Currency, error) {
+	return b.currencies, nil
+}
+
+// expensesWithIDs returns expenses with the given ids
+func expensesWithIDs(ids ...string) []Expense {
+	expenses := make([]Expense, 0, len(ids))
+	for _, id := range ids {
+		expenses = append(expenses, Expense{ID: id})
+	}
+	return expenses
+}
+
+// pagedExpenses returns the page of expenses e1..eN of a query
+func pagedExpenses(total int, q ExpensesQuery) []Expense {
+	var ids []string
+	for i := (q.Page-1)*q.PageSize + 1; i <= q.Page*q.PageSize && i <= total; i++ {
+		ids = append(ids, "e"+strconv.Itoa(i))
+	}
+	return expensesWithIDs(ids...)
+}
+
+func TestExpensesIterator(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   ExpensesQuery
+		limit   int
+		getAll  func(q ExpensesQuery) (ExpensesPage, error)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "total without page echo",
+			query: ExpensesQuery{PageSize: 1},
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				return ExpensesPage{Expenses: pagedExpenses(3, q), PageSize: q.PageSize, Total: 3}, nil
+			},
+			want: []string{"e1", "e2", "e3"},
+		},
+		{
+			name:  "total starting at a later page",
+			query: ExpensesQuery{Page: 2, PageSize: 2},
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				return ExpensesPage{Expenses: pagedExpenses(7, q), Page: q.Page, PageSize: q.PageSize, Total: 7}, nil
+			},
+			want: []string{"e3", "e4", "e5", "e6", "e7"},
+		},
+		{
+			name:  "next page",
+			query: ExpensesQuery{PageSize: 2},
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				page := ExpensesPage{Expenses: pagedExpenses(3, q), Page: q.Page, PageSize: q.PageSize}
+				if q.Page == 1 {
+					page.NextPage = 2
+				}
+				return page, nil
+			},
+			want: []string{"e1", "e2", "e3"},
+		},
+		{
+			name: "next cursor",
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				switch q.Cursor {
+				case "":
+					return ExpensesPage{Expenses: expensesWithIDs("e1", "e2"), PageSize: 2, NextCursor: "c2"}, nil
+				case "c2":
+					return ExpensesPage{Expenses: expensesWithIDs("e3", "e4"), PageSize: 2}, nil
+				default:
+					return ExpensesPage{}, errors.New("unexpected cursor")
+				}
+			},
+			want: []string{"e1", "e2", "e3", "e4"},
+		},
+		{
+			name:  "full pages without total nor page echo",
+			query: ExpensesQuery{PageSize: 2},
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				return ExpensesPage{Expenses: pagedExpenses(5, q), PageSize: q.PageSize}, nil
+			},
+			want: []string{"e1", "e2", "e3", "e4", "e5"},
+		},
+		{
+			name:  "limit",
+			query: ExpensesQuery{PageSize: 2},
+			limit: 3,
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				return ExpensesPage{Expenses: pagedExpenses(10, q), Page: q.Page, PageSize: q.PageSize, Total: 10}, nil
+			},
+			want: []string{"e1", "e2", "e3"},
+		},
+		{
+			name:  "error",
+			query: ExpensesQuery{PageSize: 1},
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				if q.Page == 2 {
+					return ExpensesPage{}, errors.New("backend down")
+				}
+				return ExpensesPage{Expenses: pagedExpenses(3, q), Page: q.Page, PageSize: q.PageSize, Total: 3}, nil
+			},
+			want:    []string{"e1"},
+			wantErr: true,
+		},
+		{
+			name: "no expenses",
+			getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				return ExpensesPage{Page: 1, PageSize: 10}, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, concurrency := range []int{1, 4} {
+			t.Run(tt.name+"/concurrency "+strconv.Itoa(concurrency), func(t *testing.T) {
+				backend := &fakeBackend{getAll: tt.getAll}
+				it := NewExpensesIterator(context.Background(), backend, tt.query, tt.limit, concurrency)
+				defer it.Close()
+
+				var got []string
+				for it.Next() {
+					got = append(got, it.Expense().ID)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got ids %v, want %v", got, tt.want)
+				}
+				if (it.Err() != nil) != tt.wantErr {
+					t.Errorf("got error %v, want error %v", it.Err(), tt.wantErr)
+				}
+			})
+		}
+	}
+}
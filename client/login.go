@@ -1,36 +1,45 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // login represents the login command which logs the user in and saves the access token to file
-func (s Switch) login() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) login() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		loginCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		email, pwd := setEmailFlag(loginCmd), setPasswordFlag(loginCmd)
+		pwdStdin := setPasswordStdinFlag(loginCmd)
 		if err := s.parseCmd(loginCmd); err != nil {
 			return err
 		}
-		if err := s.checkArgs(loginCmd, 2); err != nil {
+		if err := s.checkArgs(loginCmd, 1); err != nil {
+			return err
+		}
+		if email.value == "" {
+			return errors.New("email must be provided")
+		}
+		if err := promptPassword(pwd, *pwdStdin, false); err != nil {
 			return err
 		}
 
-		_, err := s.client.Login(email.value, pwd.value)
+		credentials, err := s.client.Login(ctx, email.value, pwd.value)
 		if err != nil {
 			return errors.Wrap(err, "could not login user")
 		}
 
-		credentials := Credentials{AccessToken: "some-access-token"}
-		err = saveCredentials(credentials)
+		err = saveCredentials(s.profile.Name, credentials)
 		if err != nil {
 			return errors.Wrap(err, "could not save credentials to file")
 		}
 
-		fmt.Println("successfully logged in")
-		return nil
+		return s.printer.printResult(commandResult{
+			ID:      credentials.UserID,
+			Status:  "logged-in",
+			Message: "successfully logged in",
+		})
 	}
 }
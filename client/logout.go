@@ -1,29 +1,37 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // logout represents the logout command which logs the user out and removes the access token from file
-func (s Switch) logout() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) logout() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		logoutCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		if err := s.parseCmd(logoutCmd); err != nil {
 			return err
 		}
 
-		err := s.client.Logout()
-		if err != nil {
+		message := "successfully logged out the user"
+		err := s.client.Logout(ctx)
+		// a session the backend no longer accepts is already revoked, only the local credentials are left
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.IsUnauthorized(), errors.Is(err, errNoRefreshToken):
+			message = "session already expired on the backend, removed the local credentials"
+		case err != nil:
 			return errors.Wrap(err, "could not log out the user")
 		}
-		if err := saveCredentials(Credentials{}); err != nil {
+		if err := removeCredentials(s.profile.Name); err != nil {
 			return errors.Wrap(err, "could not clear credentials from file")
 		}
 
-		fmt.Println("successfully logged out the user")
-		return nil
+		return s.printer.printResult(commandResult{
+			Status:  "logged-out",
+			Message: message,
+		})
 	}
 }
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+// maxDecimalDigits is the maximum number of digits of an amount, so that it always fits in an int64
+const maxDecimalDigits = 18
+
+// Money represents an exact amount of money in the minor units of its currency, e.g. 1999 USD is 19.99 USD.
+// It carries the decimal places of its currency, so that it formats the same wherever it is printed
+type Money struct {
+	Amount     int64
+	Currency   string
+	MinorUnits int
+}
+
+// zeroMoney returns a zero amount of a currency
+func zeroMoney(c currency.Currency) Money {
+	return Money{Currency: c.Code, MinorUnits: c.MinorUnits}
+}
+
+// ParseMoney parses a locale independent amount like 1234.5 or 1,234.50 of a given currency,
+// amounts with more decimal places than the currency has are rejected
+func ParseMoney(amount string, c currency.Currency) (Money, error) {
+	d, err := parseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return d.money(c)
+}
+
+// Decimal formats the amount exactly with the decimal places of the currency, e.g. 19.90
+func (m Money) Decimal() string {
+	return decimal{unscaled: m.Amount, scale: m.MinorUnits}.String()
+}
+
+func (m Money) String() string {
+	return strings.TrimSpace(m.Decimal() + " " + m.Currency)
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of the amount
+func (m Money) Sign() int {
+	switch {
+	case m.Amount < 0:
+		return -1
+	case m.Amount > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Neg returns the negated amount
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency, MinorUnits: m.MinorUnits}
+}
+
+// Add returns the sum of two amounts of the same currency
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency || m.MinorUnits != o.MinorUnits {
+		return Money{}, errors.Errorf("could not add %s to %s amounts", o.Currency, m.Currency)
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, errors.New("amount is out of range")
+	}
+	return Money{Amount: sum, Currency: m.Currency, MinorUnits: m.MinorUnits}, nil
+}
+
+// moneyFromNumber converts a json number sent by the backend to money, rounding half away from zero
+// to the decimal places of the currency, so that prices stored as floats do not fail the decoding
+func moneyFromNumber(number string, c currency.Currency) (Money, error) {
+	if number == "" {
+		return zeroMoney(c), nil
+	}
+	r, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return Money{}, errors.Errorf("invalid amount '%s'", number)
+	}
+	return moneyFromRat(r, c)
+}
+
+// moneyFromRat converts an exact amount to money, rounding half away from zero to the decimal places of the currency
+func moneyFromRat(amount *big.Rat, c currency.Currency) (Money, error) {
+	r := new(big.Rat).Mul(amount, new(big.Rat).SetInt(pow10(c.MinorUnits)))
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	if !q.IsInt64() {
+		return Money{}, errors.Errorf("amount '%s' is out of range", amount.FloatString(c.MinorUnits))
+	}
+	return Money{Amount: q.Int64(), Currency: c.Code, MinorUnits: c.MinorUnits}, nil
+}
+
+// decimal represents an exact decimal number which is not bound to a currency yet, e.g. a price flag
+type decimal struct {
+	unscaled int64
+	scale    int
+}
+
+// parseDecimal parses a locale independent decimal number: digits optionally grouped by thousands with commas,
+// followed by an optional fraction after a dot, e.g. 1234.5, 1,234.50 or -0.99
+func parseDecimal(s string) (decimal, error) {
+	s = strings.TrimSpace(s)
+	invalid := errors.Errorf("invalid amount '%s', expected a number like 1234.50 or 1,234.50", s)
+
+	digits := s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	integer, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integer, fraction = digits[:i], digits[i+1:]
+		if fraction == "" {
+			return decimal{}, invalid
+		}
+	}
+	if integer == "" && fraction == "" {
+		return decimal{}, invalid
+	}
+	if strings.Contains(integer, ",") {
+		groups := strings.Split(integer, ",")
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return decimal{}, invalid
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return decimal{}, invalid
+			}
+		}
+		integer = strings.Join(groups, "")
+	}
+	for _, c := range integer + fraction {
+		if c < '0' || c > '9' {
+			return decimal{}, invalid
+		}
+	}
+
+	all := strings.TrimLeft(integer+fraction, "0")
+	if len(all) > maxDecimalDigits {
+		return decimal{}, errors.Errorf("amount '%s' is out of range", s)
+	}
+	var unscaled int64
+	if all != "" {
+		v, err := strconv.ParseInt(all, 10, 64)
+		if err != nil {
+			return decimal{}, invalid
+		}
+		unscaled = v
+	}
+	if strings.HasPrefix(s, "-") {
+		unscaled = -unscaled
+	}
+	return decimal{unscaled: unscaled, scale: len(fraction)}, nil
+}
+
+func (d decimal) String() string {
+	sign := ""
+	unscaled := d.unscaled
+	if unscaled < 0 {
+		sign, unscaled = "-", -unscaled
+	}
+	digits := strconv.FormatInt(unscaled, 10)
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+}
+
+// sign returns -1, 0 or 1 depending on the sign of the number
+func (d decimal) sign() int {
+	return Money{Amount: d.unscaled}.Sign()
+}
+
+// cmp compares two decimals, returning -1, 0 or 1
+func (d decimal) cmp(o decimal) int {
+	scale := d.scale
+	if o.scale > scale {
+		scale = o.scale
+	}
+	a := new(big.Int).Mul(big.NewInt(d.unscaled), pow10(scale-d.scale))
+	b := new(big.Int).Mul(big.NewInt(o.unscaled), pow10(scale-o.scale))
+	return a.Cmp(b)
+}
+
+// money converts the decimal to an amount of a currency, it must not have more decimal places than the currency
+func (d decimal) money(c currency.Currency) (Money, error) {
+	places := c.MinorUnits
+	unscaled, scale := d.unscaled, d.scale
+	for scale > places && unscaled%10 == 0 {
+		unscaled, scale = unscaled/10, scale-1
+	}
+	if scale > places {
+		return Money{}, fmt.Errorf("%s amounts must have at most %d decimal places", c.Code, places)
+	}
+	factor := pow10(places - scale)
+	amount := new(big.Int).Mul(big.NewInt(unscaled), factor)
+	if !amount.IsInt64() || amount.Int64() == math.MinInt64 {
+		return Money{}, errors.Errorf("amount '%s' is out of range", d)
+	}
+	return Money{Amount: amount.Int64(), Currency: c.Code, MinorUnits: places}, nil
+}
+
+// pow10 returns 10 to the power of n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+// testMoney returns an amount of an ISO 4217 currency
+func testMoney(amount int64, code string) Money {
+	c, _ := currency.ISO4217().Lookup(code)
+	return Money{Amount: amount, Currency: code, MinorUnits: c.MinorUnits}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    decimal
+		wantErr bool
+	}{
+		{input: "1234.5", want: decimal{unscaled: 12345, scale: 1}},
+		{input: "1,234.50", want: decimal{unscaled: 123450, scale: 2}},
+		{input: "1,234,567", want: decimal{unscaled: 1234567}},
+		{input: "-0.99", want: decimal{unscaled: -99, scale: 2}},
+		{input: "+7", want: decimal{unscaled: 7}},
+		{input: " 0.10 ", want: decimal{unscaled: 10, scale: 2}},
+		{input: ".5", want: decimal{unscaled: 5, scale: 1}},
+		{input: "000012.000", want: decimal{unscaled: 12000, scale: 3}},
+		{input: "999999999999999999", want: decimal{unscaled: 999999999999999999}},
+		{input: "1234567890123456789", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "-", wantErr: true},
+		{input: "1.", wantErr: true},
+		{input: "1.2.3", wantErr: true},
+		{input: "1,23.00", wantErr: true},
+		{input: "1234,567", wantErr: true},
+		{input: ",123", wantErr: true},
+		{input: "1 234", wantErr: true},
+		{input: "1,234.5,0", wantErr: true},
+		{input: "12e3", wantErr: true},
+		{input: "1.234,50", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseDecimal(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{input: "19.99", currency: "USD", want: testMoney(1999, "USD")},
+		{input: "19.9", currency: "USD", want: testMoney(1990, "USD")},
+		{input: "19.900", currency: "USD", want: testMoney(1990, "USD")},
+		{input: "19.999", currency: "USD", wantErr: true},
+		{input: "1000", currency: "JPY", want: testMoney(1000, "JPY")},
+		{input: "1000.5", currency: "JPY", wantErr: true},
+		{input: "1.234", currency: "KWD", want: testMoney(1234, "KWD")},
+		{input: "99999999999999999", currency: "USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+" "+tt.currency, func(t *testing.T) {
+			got, err := ParseMoney(tt.input, knownCurrency(currency.ISO4217(), tt.currency))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyFromRat(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{amount: "19.99", currency: "USD", want: 1999},
+		{amount: "0.005", currency: "USD", want: 1},
+		{amount: "0.0049", currency: "USD", want: 0},
+		{amount: "-0.005", currency: "USD", want: -1},
+		{amount: "-0.0049", currency: "USD", want: 0},
+		{amount: "2.675", currency: "USD", want: 268},
+		{amount: "1/3", currency: "USD", want: 33},
+		{amount: "2/3", currency: "USD", want: 67},
+		{amount: "-2/3", currency: "USD", want: -67},
+		{amount: "999.5", currency: "JPY", want: 1000},
+		{amount: "999.4999", currency: "JPY", want: 999},
+		{amount: "1.2345", currency: "KWD", want: 1235},
+		{amount: "100000000000000000", currency: "USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			r, ok := new(big.Rat).SetString(tt.amount)
+			if !ok {
+				t.Fatalf("invalid amount %s", tt.amount)
+			}
+			got, err := moneyFromRat(r, knownCurrency(currency.ISO4217(), tt.currency))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (got.Amount != tt.want || got.Currency != tt.currency) {
+				t.Errorf("got %+v, want %d %s", got, tt.want, tt.currency)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{money: testMoney(1999, "USD"), want: "19.99"},
+		{money: testMoney(5, "USD"), want: "0.05"},
+		{money: testMoney(-5, "USD"), want: "-0.05"},
+		{money: testMoney(1000, "JPY"), want: "1000"},
+		{money: testMoney(1, "KWD"), want: "0.001"},
+		{money: testMoney(0, "EUR"), want: "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.money.Decimal(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
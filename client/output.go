@@ -0,0 +1,424 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Output formats supported by the output flag
+const (
+	TableOutput    = "table"
+	JSONOutput     = "json"
+	NDJSONOutput   = "ndjson"
+	CSVOutput      = "csv"
+	YAMLOutput     = "yaml"
+	TemplateOutput = "template"
+)
+
+var outputFormats = []string{TableOutput, JSONOutput, NDJSONOutput, CSVOutput, YAMLOutput, TemplateOutput}
+
+// commandResult represents the result of a command which does not fetch expenses
+type commandResult struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// printer renders the results of the commands in the selected output format
+type printer struct {
+	format string
+	tmpl   *template.Template
+	out    io.Writer
+	// converter converts the printed expenses to another currency, if any
+	converter *Converter
+}
+
+// newPrinter creates a printer for a given output format, a non empty template implies the template format
+func newPrinter(format, tmpl string, out io.Writer) (printer, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" && tmpl != "" {
+		format = TemplateOutput
+	}
+	if format == "" {
+		format = TableOutput
+	}
+
+	p := printer{format: format, out: out}
+	switch format {
+	case TableOutput, JSONOutput, NDJSONOutput, CSVOutput, YAMLOutput:
+		return p, nil
+	case TemplateOutput:
+		if tmpl == "" {
+			return printer{}, errors.New("template must be provided for the template output")
+		}
+		t, err := template.New("output").Parse(tmpl)
+		if err != nil {
+			return printer{}, errors.Wrap(err, "could not parse output template")
+		}
+		p.tmpl = t
+		return p, nil
+	default:
+		return printer{}, errors.New("output must be one of: " + strings.Join(outputFormats, ","))
+	}
+}
+
+// printExpensesPage prints a page of expenses, the json and yaml formats render the whole page
+func (p printer) printExpensesPage(page ExpensesPage) error {
+	if p.format == TableOutput {
+		fmt.Fprintf(
+			p.out,
+			"expenses fetched successfully (page: %d, page size: %d, total: %d):\n",
+			page.Page,
+			page.PageSize,
+			page.Total,
+		)
+	}
+	if p.converter != nil {
+		converted, err := p.converter.ConvertPage(page)
+		if err != nil {
+			return err
+		}
+		return p.printExpenses(converted, page.Expenses)
+	}
+	return p.printExpenses(page, page.Expenses)
+}
+
+// printExpenseList prints a list of expenses, along with their converted total when converting
+func (p printer) printExpenseList(expenses []Expense) error {
+	if p.converter != nil {
+		converted, err := p.converter.ConvertAll(expenses)
+		if err != nil {
+			return err
+		}
+		return p.printExpenses(converted, expenses)
+	}
+	return p.printExpenses(expenses, expenses)
+}
+
+// printExpenses prints a list of expenses, value is rendered by the json and yaml formats.
+// Like every command printing expenses, the template format is executed once per expense
+func (p printer) printExpenses(value interface{}, expenses []Expense) error {
+	switch p.format {
+	case TableOutput, NDJSONOutput, CSVOutput, TemplateOutput:
+		w := p.newExpensesWriter()
+		for _, e := range expenses {
+			if err := w.write(e); err != nil {
+				return err
+			}
+		}
+		return w.close()
+	default:
+		return p.printValue(value)
+	}
+}
+
+// printExpensesStream prints every expense of the iterator as soon as it is fetched
+func (p printer) printExpensesStream(it *ExpensesIterator) error {
+	defer it.Close()
+	w := p.newExpensesWriter()
+	for it.Next() {
+		if err := w.write(it.Expense()); err != nil {
+			return err
+		}
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+// printExpense prints a single expense, the table format prints the human friendly message first
+func (p printer) printExpense(message string, expense Expense) error {
+	if p.format == TableOutput {
+		fmt.Fprintln(p.out, message)
+	}
+	return p.printExpenses(expense, []Expense{expense})
+}
+
+// printID prints only the id of an expense, regardless of the output format
+func (p printer) printID(id string) error {
+	_, err := fmt.Fprintln(p.out, id)
+	return err
+}
+
+// printResult prints the result of a command, the table format prints the human friendly message
+func (p printer) printResult(r commandResult) error {
+	switch p.format {
+	case TableOutput:
+		fmt.Fprintln(p.out, r.Message)
+		return nil
+	case CSVOutput:
+		w := csv.NewWriter(p.out)
+		_ = w.Write([]string{"id", "status", "message"})
+		_ = w.Write([]string{r.ID, r.Status, r.Message})
+		w.Flush()
+		return errors.Wrap(w.Error(), "could not write csv")
+	default:
+		return p.printValue(r)
+	}
+}
+
+// printResults prints the results of a command which processes several items,
+// the table format prints the human friendly message of every result
+func (p printer) printResults(results []commandResult) error {
+	switch p.format {
+	case TableOutput:
+		for _, r := range results {
+			fmt.Fprintln(p.out, r.Message)
+		}
+		return nil
+	case NDJSONOutput:
+		enc := json.NewEncoder(p.out)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return errors.Wrap(err, "could not encode json")
+			}
+		}
+		return nil
+	case CSVOutput:
+		w := csv.NewWriter(p.out)
+		_ = w.Write([]string{"id", "status", "message"})
+		for _, r := range results {
+			_ = w.Write([]string{r.ID, r.Status, r.Message})
+		}
+		w.Flush()
+		return errors.Wrap(w.Error(), "could not write csv")
+	default:
+		return p.printValue(results)
+	}
+}
+
+// printValue prints a value in the json, ndjson, yaml or template format
+func (p printer) printValue(v interface{}) error {
+	switch p.format {
+	case YAMLOutput:
+		bs, err := marshalYAML(v)
+		if err != nil {
+			return errors.Wrap(err, "could not encode yaml")
+		}
+		_, err = p.out.Write(bs)
+		return err
+	case TemplateOutput:
+		if err := p.tmpl.Execute(p.out, v); err != nil {
+			return errors.Wrap(err, "could not execute output template")
+		}
+		fmt.Fprintln(p.out)
+		return nil
+	default:
+		if err := json.NewEncoder(p.out).Encode(v); err != nil {
+			return errors.Wrap(err, "could not encode json")
+		}
+		return nil
+	}
+}
+
+// formatTime formats a timestamp as RFC3339, zero timestamps are left empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// expensesWriter writes expenses one by one in the selected output format,
+// with a converter the converted price is written next to the original one and the converted total last
+type expensesWriter struct {
+	p     printer
+	tw    *tabwriter.Writer
+	cw    *csv.Writer
+	count int
+	// total sums the converted prices
+	total Money
+}
+
+func (p printer) newExpensesWriter() *expensesWriter {
+	w := &expensesWriter{
+		p:  p,
+		tw: tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0),
+		cw: csv.NewWriter(p.out),
+	}
+	if p.converter != nil {
+		w.total = p.converter.zero()
+	}
+	return w
+}
+
+var (
+	expenseCSVHeader          = []string{"id", "title", "price", "currency", "created_at", "updated_at"}
+	convertedExpenseCSVHeader = []string{"id", "title", "price", "currency", "converted_price", "converted_currency", "rate", "created_at", "updated_at"}
+)
+
+// header returns the csv header of the written expenses
+func (w *expensesWriter) header() []string {
+	if w.p.converter == nil {
+		return expenseCSVHeader
+	}
+	return convertedExpenseCSVHeader
+}
+
+// row returns the csv fields of an expense, along with the value rendered by the json, yaml and template formats,
+// which is the Expense or the ConvertedExpense when converting
+func (w *expensesWriter) row(e Expense) ([]string, interface{}, error) {
+	if w.p.converter == nil {
+		return []string{
+			e.ID,
+			e.Title,
+			e.Price.Decimal(),
+			e.Currency(),
+			formatTime(e.CreatedAt),
+			formatTime(e.UpdatedAt),
+		}, e, nil
+	}
+
+	ce, err := w.p.converter.Convert(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	if w.total, err = w.total.Add(ce.Converted); err != nil {
+		return nil, nil, err
+	}
+	return []string{
+		e.ID,
+		e.Title,
+		e.Price.Decimal(),
+		e.Currency(),
+		ce.Converted.Decimal(),
+		ce.Converted.Currency,
+		ce.Rate,
+		formatTime(e.CreatedAt),
+		formatTime(e.UpdatedAt),
+	}, ce, nil
+}
+
+func (w *expensesWriter) write(e Expense) error {
+	defer func() { w.count++ }()
+	out := w.p.out
+
+	row, v, err := w.row(e)
+	if err != nil {
+		return err
+	}
+	switch w.p.format {
+	case TableOutput:
+		if w.count == 0 {
+			header := strings.ToUpper(strings.Join(w.header(), "\t"))
+			fmt.Fprintln(w.tw, strings.ReplaceAll(header, "_", " "))
+		}
+		_, err := fmt.Fprintln(w.tw, strings.Join(row, "\t"))
+		return err
+	case CSVOutput:
+		if w.count == 0 {
+			_ = w.cw.Write(w.header())
+		}
+		return w.cw.Write(row)
+	case JSONOutput:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "could not encode json")
+		}
+		sep := ","
+		if w.count == 0 {
+			sep = w.jsonOpening()
+		}
+		_, err = fmt.Fprintf(out, "%s%s", sep, bs)
+		return err
+	case YAMLOutput:
+		bs, err := marshalYAML([]interface{}{v})
+		if err != nil {
+			return errors.Wrap(err, "could not encode yaml")
+		}
+		_, err = out.Write(bs)
+		return err
+	case TemplateOutput:
+		if err := w.p.tmpl.Execute(out, v); err != nil {
+			return errors.Wrap(err, "could not execute output template")
+		}
+		_, err := fmt.Fprintln(out)
+		return err
+	default:
+		if err := json.NewEncoder(out).Encode(v); err != nil {
+			return errors.Wrap(err, "could not encode json")
+		}
+		return nil
+	}
+}
+
+func (w *expensesWriter) close() error {
+	out := w.p.out
+	switch w.p.format {
+	case TableOutput:
+		if w.count == 0 {
+			fmt.Fprintln(out, "no expenses found")
+			return nil
+		}
+		if err := w.tw.Flush(); err != nil {
+			return err
+		}
+		if w.p.converter != nil {
+			fmt.Fprintf(out, "total: %s\n", w.total)
+		}
+		return nil
+	case NDJSONOutput:
+		if w.p.converter == nil {
+			return nil
+		}
+		err := json.NewEncoder(out).Encode(struct {
+			Summary conversionSummary `json:"summary"`
+		}{w.summary()})
+		return errors.Wrap(err, "could not encode json")
+	case CSVOutput:
+		if w.count == 0 {
+			_ = w.cw.Write(w.header())
+		}
+		w.cw.Flush()
+		return errors.Wrap(w.cw.Error(), "could not write csv")
+	case JSONOutput:
+		if w.count == 0 {
+			fmt.Fprint(out, w.jsonOpening())
+		}
+		if w.p.converter == nil {
+			_, err := fmt.Fprintln(out, "]")
+			return err
+		}
+		summary := w.summary()
+		_, err := fmt.Fprintf(out, `],"converted_total":%s,"converted_currency":%q}`+"\n", summary.ConvertedTotal, summary.ConvertedCurrency)
+		return err
+	case YAMLOutput:
+		if w.count == 0 {
+			_, err := fmt.Fprintln(out, "[]")
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonOpening returns the opening of the json output, an object holding the expenses and their total when converting
+func (w *expensesWriter) jsonOpening() string {
+	if w.p.converter == nil {
+		return "["
+	}
+	return `{"expenses":[`
+}
+
+// conversionSummary represents the converted total of the written expenses
+type conversionSummary struct {
+	Count             int         `json:"count"`
+	ConvertedTotal    json.Number `json:"converted_total"`
+	ConvertedCurrency string      `json:"converted_currency"`
+}
+
+func (w *expensesWriter) summary() conversionSummary {
+	return conversionSummary{
+		Count:             w.count,
+		ConvertedTotal:    json.Number(w.total.Decimal()),
+		ConvertedCurrency: w.total.Currency,
+	}
+}
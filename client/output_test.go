@@ -0,0 +1,49 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestPrinterTemplatePerExpense(t *testing.T) {
+	expenses := []Expense{
+		{ID: "e1", Price: testMoney(1000, "EUR")},
+		{ID: "e2", Price: testMoney(250, "USD")},
+	}
+	const want = "e1 10.00 EUR\ne2 2.50 USD\n"
+
+	tests := []struct {
+		name  string
+		print func(p printer) error
+	}{
+		{name: "page", print: func(p printer) error {
+			return p.printExpensesPage(ExpensesPage{Expenses: expenses, Page: 1, PageSize: 2, Total: 2})
+		}},
+		{name: "list", print: func(p printer) error {
+			return p.printExpenseList(expenses)
+		}},
+		{name: "stream", print: func(p printer) error {
+			backend := &fakeBackend{getAll: func(q ExpensesQuery) (ExpensesPage, error) {
+				return ExpensesPage{Expenses: expenses, Page: 1, PageSize: 2, Total: 2}, nil
+			}}
+			return p.printExpensesStream(NewExpensesIterator(context.Background(), backend, ExpensesQuery{PageSize: 2}, 0, 1))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p, err := newPrinter("", "{{.ID}} {{.Price}}", &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.print(p); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != want {
+				t.Errorf("got %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// promptPassword fills in the password when it was not provided with the password flag,
+// either by reading the first line of stdin or by prompting on the terminal without echo
+func promptPassword(pwd *passwordFlag, fromStdin, confirm bool) error {
+	if pwd.value != "" {
+		if fromStdin {
+			return errors.New("--password and --password-stdin are mutually exclusive")
+		}
+		return nil
+	}
+
+	var password string
+	switch {
+	case fromStdin:
+		p, err := readPasswordLine(os.Stdin)
+		if err != nil {
+			return err
+		}
+		password = p
+	case term.IsTerminal(int(os.Stdin.Fd())):
+		p, err := readPasswordTerminal("Password: ")
+		if err != nil {
+			return err
+		}
+		if confirm {
+			confirmation, err := readPasswordTerminal("Confirm password: ")
+			if err != nil {
+				return err
+			}
+			if confirmation != p {
+				return errors.New("passwords do not match")
+			}
+		}
+		password = p
+	default:
+		return errors.New("password must be provided with --password, --password-stdin or on an interactive terminal")
+	}
+
+	return pwd.Set(password)
+}
+
+// readPasswordTerminal prompts for a password on the terminal without echoing the input
+func readPasswordTerminal(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	bs, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", errors.Wrap(err, "could not read password")
+	}
+	return string(bs), nil
+}
+
+// readPasswordLine reads the password from the first line of r one byte at a time,
+// so that the rest of r is left unread for the command, e.g. ids or expenses piped after the password
+func readPasswordLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "could not read password from stdin")
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestReadPasswordLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantRest string
+	}{
+		{name: "password only", input: "secret", want: "secret"},
+		{name: "trailing newline", input: "secret\n", want: "secret"},
+		{name: "crlf", input: "secret\r\n", want: "secret"},
+		{name: "piped data after the password", input: "secret\ne1\ne2\n", want: "secret", wantRest: "e1\ne2\n"},
+		{name: "empty", input: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := strings.NewReader(tt.input)
+			got, err := readPasswordLine(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got password %q, want %q", got, tt.want)
+			}
+			rest, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(rest) != tt.wantRest {
+				t.Errorf("got unread input %q, want %q", rest, tt.wantRest)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// profileCmd represents the profile command family which manages the named backend profiles
+func (s Switch) profileCmd() func(context.Context, string) error {
+	subCommands := map[string]func(string) error{
+		"add":    s.profileAdd,
+		"list":   s.profileList,
+		"use":    s.profileUse,
+		"remove": s.profileRemove,
+	}
+	return func(ctx context.Context, cmdName string) error {
+		subCmdName := flag.Arg(1)
+		subCmd, ok := subCommands[subCmdName]
+		if !ok {
+			names := make([]string, 0, len(subCommands))
+			for name := range subCommands {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(os.Stderr, "Usage of %s %s:\n<%s> [<args>]\n", os.Args[0], cmdName, strings.Join(names, "|"))
+			if subCmdName == "" {
+				return errors.New("profile sub-command must be provided")
+			}
+			return fmt.Errorf("invalid profile sub-command '%s'", subCmdName)
+		}
+		return subCmd(cmdName + " " + subCmdName)
+	}
+}
+
+// profileAdd adds a new or replaces an existing profile: profile add <name> [<args>]
+func (s Switch) profileAdd(cmdName string) error {
+	addCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
+	backend := addCmd.String("backend", DefaultBackendURI, "Expenses REST API URL")
+	scheme := addCmd.String("auth", BearerAuthScheme, "Auth scheme: bearer, api-key or basic")
+	header := addCmd.String("api-key-header", defaultAPIKeyHeader, "Header used by the api-key auth scheme")
+	apiKey := addCmd.String("api-key", "", "API key used by the api-key auth scheme (visible in the process list, prefer --api-key-stdin or "+APIKeyEnv+")")
+	apiKeyStdin := addCmd.Bool("api-key-stdin", false, "Read the API key from the first line of stdin")
+	username := addCmd.String("basic-user", "", "Username used by the basic auth scheme")
+	password := addCmd.String("basic-password", "", "Password used by the basic auth scheme (visible in the process list, prefer --basic-password-stdin or "+BasicPasswordEnv+")")
+	passwordStdin := addCmd.Bool("basic-password-stdin", false, "Read the basic auth password from the first line of stdin")
+	caCert := addCmd.String("ca-cert", "", "Path of a PEM CA bundle used to verify the backend")
+	clientCert := addCmd.String("client-cert", "", "Path of the PEM client certificate used for mTLS")
+	clientKey := addCmd.String("client-key", "", "Path of the PEM client key used for mTLS")
+	tlsMinVersion := addCmd.String("tls-min-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	insecure := addCmd.Bool("insecure", false, "Skip the backend certificate verification (self-signed dev backends only)")
+	currencies := addCmd.String("currencies", "", "Comma separated currency codes the profile is restricted to (defaults to every ISO 4217 currency)")
+	backendCurrencies := addCmd.Bool("backend-currencies", false, "Restrict the currencies to the ones listed by the backend currencies endpoint")
+
+	name, err := profileNameArg()
+	if err != nil {
+		return err
+	}
+	if err := addCmd.Parse(flag.Args()[3:]); err != nil {
+		return errors.Wrap(err, "could not parse '"+cmdName+"' flags")
+	}
+
+	uri, err := url.Parse(*backend)
+	if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") || uri.Host == "" {
+		return errors.New("backend must be a valid http or https URL")
+	}
+	profile := Profile{
+		Name:       name,
+		BackendURI: strings.TrimRight(*backend, "/"),
+		Auth: AuthConfig{
+			Scheme:   *scheme,
+			Header:   *header,
+			APIKey:   *apiKey,
+			Username: *username,
+			Password: *password,
+		},
+		TLS: TLSConfig{
+			CACert:     *caCert,
+			ClientCert: *clientCert,
+			ClientKey:  *clientKey,
+			MinVersion: *tlsMinVersion,
+		},
+		Currencies: CurrenciesConfig{
+			FromBackend: *backendCurrencies,
+		},
+	}
+	if *insecure {
+		profile.TLS.Insecure = insecure
+	}
+	if err := ReadAuthSecrets(&profile.Auth, *apiKeyStdin, *passwordStdin); err != nil {
+		return err
+	}
+	for _, code := range strings.Split(*currencies, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			profile.Currencies.Allowed = append(profile.Currencies.Allowed, code)
+		}
+	}
+	if _, err := NewAuthScheme(profile.Name, profile.Auth); err != nil {
+		return errors.Wrap(err, "invalid auth scheme")
+	}
+	if _, err := profile.TLS.build(); err != nil {
+		return errors.Wrap(err, "invalid TLS settings")
+	}
+	if _, err := newCurrencyRegistry(profile.Currencies); err != nil {
+		return err
+	}
+
+	config, err := readProfiles()
+	if err != nil {
+		return err
+	}
+	config.Profiles[name] = profile
+	if err := saveProfiles(config); err != nil {
+		return err
+	}
+
+	return s.printer.printResult(commandResult{
+		ID:      name,
+		Status:  "saved",
+		Message: fmt.Sprintf("profile '%s' saved successfully", name),
+	})
+}
+
+// profileSummary represents a profile as listed by profile list, leaving out its secrets
+type profileSummary struct {
+	Name       string `json:"name"`
+	BackendURI string `json:"backend_uri"`
+	AuthScheme string `json:"auth_scheme"`
+	Active     bool   `json:"active"`
+}
+
+// profileList lists all the configured profiles and marks the active one
+func (s Switch) profileList(cmdName string) error {
+	listCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
+	if err := listCmd.Parse(flag.Args()[2:]); err != nil {
+		return errors.Wrap(err, "could not parse '"+cmdName+"' flags")
+	}
+
+	config, err := readProfiles()
+	if err != nil {
+		return err
+	}
+	profiles := config.sortedProfiles()
+	summaries := make([]profileSummary, 0, len(profiles))
+	for _, p := range profiles {
+		scheme := p.Auth.Scheme
+		if scheme == "" {
+			scheme = BearerAuthScheme
+		}
+		summaries = append(summaries, profileSummary{
+			Name:       p.Name,
+			BackendURI: p.BackendURI,
+			AuthScheme: scheme,
+			Active:     p.Name == config.currentProfileName(),
+		})
+	}
+
+	switch s.printer.format {
+	case TableOutput:
+		if len(summaries) == 0 {
+			fmt.Fprintln(s.printer.out, "no profiles configured")
+			return nil
+		}
+		for _, p := range summaries {
+			marker := " "
+			if p.Active {
+				marker = "*"
+			}
+			fmt.Fprintf(s.printer.out, "%s %s\t%s\t%s\n", marker, p.Name, p.BackendURI, p.AuthScheme)
+		}
+		return nil
+	case NDJSONOutput:
+		enc := json.NewEncoder(s.printer.out)
+		for _, p := range summaries {
+			if err := enc.Encode(p); err != nil {
+				return errors.Wrap(err, "could not encode json")
+			}
+		}
+		return nil
+	case CSVOutput:
+		w := csv.NewWriter(s.printer.out)
+		_ = w.Write([]string{"name", "backend_uri", "auth_scheme", "active"})
+		for _, p := range summaries {
+			_ = w.Write([]string{p.Name, p.BackendURI, p.AuthScheme, strconv.FormatBool(p.Active)})
+		}
+		w.Flush()
+		return errors.Wrap(w.Error(), "could not write csv")
+	default:
+		return s.printer.printValue(summaries)
+	}
+}
+
+// profileUse makes the given profile the active one: profile use <name>
+func (s Switch) profileUse(string) error {
+	name, err := profileNameArg()
+	if err != nil {
+		return err
+	}
+	config, err := readProfiles()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Profiles[name]; !ok {
+		return errors.Errorf("profile '%s' does not exist", name)
+	}
+	config.Current = name
+	if err := saveProfiles(config); err != nil {
+		return err
+	}
+
+	return s.printer.printResult(commandResult{
+		ID:      name,
+		Status:  "active",
+		Message: fmt.Sprintf("switched to profile '%s'", name),
+	})
+}
+
+// profileRemove removes the given profile along with its credentials: profile remove <name>
+func (s Switch) profileRemove(string) error {
+	name, err := profileNameArg()
+	if err != nil {
+		return err
+	}
+	config, err := readProfiles()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Profiles[name]; !ok {
+		return errors.Errorf("profile '%s' does not exist", name)
+	}
+	delete(config.Profiles, name)
+	if config.Current == name {
+		config.Current = ""
+	}
+	if err := saveProfiles(config); err != nil {
+		return err
+	}
+	if err := removeCredentials(name); err != nil {
+		return err
+	}
+
+	return s.printer.printResult(commandResult{
+		ID:      name,
+		Status:  "removed",
+		Message: fmt.Sprintf("profile '%s' removed successfully", name),
+	})
+}
+
+// profileNameArg reads and validates the profile name positional argument
+func profileNameArg() (string, error) {
+	name := strings.TrimSpace(flag.Arg(2))
+	if name == "" || strings.HasPrefix(name, "-") {
+		return "", errors.New("profile name must be provided")
+	}
+	if strings.ContainsAny(name, `/\. `) {
+		return "", errors.New("profile name must not contain slashes, dots or spaces")
+	}
+	return name, nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	profilesFileName = "profiles.json"
+	// DefaultProfileName is the name of the profile used when none is configured
+	DefaultProfileName = "default"
+	// DefaultBackendURI is the backend URI used when neither a profile nor a flag provides one
+	DefaultBackendURI = "http://localhost:8080"
+)
+
+// Profile represents a named backend configuration with its own auth scheme and credentials
+type Profile struct {
+	Name       string           `json:"name"`
+	BackendURI string           `json:"backend_uri"`
+	Auth       AuthConfig       `json:"auth"`
+	TLS        TLSConfig        `json:"tls"`
+	Currencies CurrenciesConfig `json:"currencies"`
+}
+
+// profilesConfig represents the contents of the profiles file
+type profilesConfig struct {
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// sortedProfiles returns the configured profiles sorted by name
+func (c profilesConfig) sortedProfiles() []Profile {
+	profiles := make([]Profile, 0, len(c.Profiles))
+	for _, p := range c.Profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// currentProfileName returns the name of the active profile
+func (c profilesConfig) currentProfileName() string {
+	if c.Current != "" {
+		return c.Current
+	}
+	return DefaultProfileName
+}
+
+func profilesPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profilesFileName), nil
+}
+
+func readProfiles() (profilesConfig, error) {
+	config := profilesConfig{Profiles: map[string]Profile{}}
+	path, err := profilesPath()
+	if err != nil {
+		return profilesConfig{}, err
+	}
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return profilesConfig{}, errors.Wrap(err, "could not read profiles file")
+	}
+	if err := json.Unmarshal(bs, &config); err != nil {
+		return profilesConfig{}, errors.Wrap(err, "could not decode json profiles")
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]Profile{}
+	}
+	return config, nil
+}
+
+func saveProfiles(config profilesConfig) error {
+	path, err := profilesPath()
+	if err != nil {
+		return err
+	}
+	bs, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "could not encode json profiles")
+	}
+	if err := writeFileAtomic(path, bs, 0600); err != nil {
+		return errors.Wrap(err, "could not write profiles file")
+	}
+	return nil
+}
+
+// resolveProfile finds the profile to be used and applies the explicitly provided options on top of it
+func resolveProfile(opts Options) (Profile, error) {
+	config, err := readProfiles()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := opts.Profile
+	if name == "" {
+		name = config.currentProfileName()
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		if name != DefaultProfileName {
+			return Profile{}, errors.Errorf("profile '%s' does not exist", name)
+		}
+		profile = Profile{Name: DefaultProfileName, BackendURI: DefaultBackendURI}
+	}
+
+	if opts.BackendURI != "" {
+		profile.BackendURI = opts.BackendURI
+	}
+	if opts.Auth.Scheme != "" {
+		profile.Auth = opts.Auth
+	}
+	// secrets read from stdin also apply to the auth scheme of the profile
+	if opts.Auth.APIKey != "" {
+		profile.Auth.APIKey = opts.Auth.APIKey
+	}
+	if opts.Auth.Password != "" {
+		profile.Auth.Password = opts.Auth.Password
+	}
+	profile.TLS = profile.TLS.merge(opts.HTTP.TLS)
+	return profile, nil
+}
@@ -0,0 +1,368 @@
+package client
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
+)
+
+const (
+	rateDateLayout = "2006-01-02"
+	// rateDecimals is the number of decimal places of the displayed rates
+	rateDecimals = 6
+)
+
+// rate represents the exchange rate of a currency pair from a given date onwards
+type rate struct {
+	date  string
+	value *big.Rat
+}
+
+// rateTable represents the dated exchange rates loaded from a local file, so conversions need no network access
+type rateTable struct {
+	// pairs holds the rates of every from/to currency pair sorted by date
+	pairs map[string][]rate
+	// currencies lists the currencies having at least one rate, in order
+	currencies []string
+}
+
+// ConvertedExpense represents an expense along with its price converted to another currency
+type ConvertedExpense struct {
+	Expense
+	Converted Money
+	// Rate is the exchange rate applied to the price, rounded for display
+	Rate string
+}
+
+func (e ConvertedExpense) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		expenseJSON
+		ConvertedPrice    json.Number `json:"converted_price"`
+		ConvertedCurrency string      `json:"converted_currency"`
+		Rate              json.Number `json:"rate"`
+	}{
+		expenseJSON: expenseJSON{
+			ID:        e.ID,
+			Title:     e.Title,
+			Currency:  e.Price.Currency,
+			Price:     json.Number(e.Price.Decimal()),
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		},
+		ConvertedPrice:    json.Number(e.Converted.Decimal()),
+		ConvertedCurrency: e.Converted.Currency,
+		Rate:              json.Number(e.Rate),
+	})
+}
+
+// ConvertedExpenses represents a list of expenses converted to a currency, along with their converted total
+type ConvertedExpenses struct {
+	Expenses []ConvertedExpense
+	Total    Money
+}
+
+func (c ConvertedExpenses) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Expenses          []ConvertedExpense `json:"expenses"`
+		ConvertedTotal    json.Number        `json:"converted_total"`
+		ConvertedCurrency string             `json:"converted_currency"`
+	}{
+		Expenses:          c.Expenses,
+		ConvertedTotal:    json.Number(c.Total.Decimal()),
+		ConvertedCurrency: c.Total.Currency,
+	})
+}
+
+// ConvertedExpensesPage represents a page of expenses converted to a currency, along with their converted total
+type ConvertedExpensesPage struct {
+	ExpensesPage
+	Expenses          []ConvertedExpense `json:"expenses"`
+	ConvertedTotal    json.Number        `json:"converted_total"`
+	ConvertedCurrency string             `json:"converted_currency"`
+}
+
+// Converter converts the prices of expenses to a currency, as of the date each expense was created.
+// The exchange rates are loaded from a local file, so conversions need no network access
+type Converter struct {
+	rates *rateTable
+	to    currency.Currency
+}
+
+// NewConverter creates a Converter to a currency with the rates of a csv file with a date,from,to,rate header,
+// or of a json array of objects with the date, from, to and rate fields. The converted prices are rounded
+// to the decimal places of the currency in the known currencies, ISO 4217 when nil
+func NewConverter(to, ratesPath string, known *currency.Registry) (*Converter, error) {
+	to = strings.ToUpper(strings.TrimSpace(to))
+	if err := (currency.Currency{Code: to}).Validate(); err != nil {
+		return nil, err
+	}
+	if known == nil {
+		known = currency.ISO4217()
+	}
+	rates, err := loadRates(ratesPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load rates")
+	}
+	return &Converter{rates: rates, to: knownCurrency(known, to)}, nil
+}
+
+// Currency returns the currency the prices are converted to
+func (c *Converter) Currency() string {
+	return c.to.Code
+}
+
+// zero returns a zero amount of the currency the prices are converted to
+func (c *Converter) zero() Money {
+	return zeroMoney(c.to)
+}
+
+// Convert converts the price of an expense as of the date the expense was created
+func (c *Converter) Convert(e Expense) (ConvertedExpense, error) {
+	r, err := c.rates.lookup(e.Currency(), c.to.Code, e.CreatedAt)
+	if err != nil {
+		return ConvertedExpense{}, errors.Wrapf(err, "could not convert expense with id: %s", e.ID)
+	}
+	amount := new(big.Rat).SetFrac(big.NewInt(e.Price.Amount), pow10(e.Price.MinorUnits))
+	converted, err := moneyFromRat(amount.Mul(amount, r), c.to)
+	if err != nil {
+		return ConvertedExpense{}, errors.Wrapf(err, "could not convert expense with id: %s", e.ID)
+	}
+	return ConvertedExpense{Expense: e, Converted: converted, Rate: formatRate(r)}, nil
+}
+
+// ConvertAll converts every expense and sums the converted prices
+func (c *Converter) ConvertAll(expenses []Expense) (ConvertedExpenses, error) {
+	converted := ConvertedExpenses{
+		Expenses: make([]ConvertedExpense, 0, len(expenses)),
+		Total:    c.zero(),
+	}
+	for _, e := range expenses {
+		ce, err := c.Convert(e)
+		if err != nil {
+			return ConvertedExpenses{}, err
+		}
+		if converted.Total, err = converted.Total.Add(ce.Converted); err != nil {
+			return ConvertedExpenses{}, err
+		}
+		converted.Expenses = append(converted.Expenses, ce)
+	}
+	return converted, nil
+}
+
+// ConvertPage converts every expense of a page and sums the converted prices
+func (c *Converter) ConvertPage(page ExpensesPage) (ConvertedExpensesPage, error) {
+	converted, err := c.ConvertAll(page.Expenses)
+	if err != nil {
+		return ConvertedExpensesPage{}, err
+	}
+	return ConvertedExpensesPage{
+		ExpensesPage:      page,
+		Expenses:          converted.Expenses,
+		ConvertedTotal:    json.Number(converted.Total.Decimal()),
+		ConvertedCurrency: c.to.Code,
+	}, nil
+}
+
+// converter returns the converter to a currency with the rates of a file, the rates file defaults
+// to the one of the config directory. Without a currency there is no conversion and the converter is nil
+func converter(to, ratesPath string, known *currency.Registry) (*Converter, error) {
+	if to == "" {
+		if ratesPath != "" {
+			return nil, errors.New("rates can only be provided along with convert-to")
+		}
+		return nil, nil
+	}
+	if ratesPath == "" {
+		path, err := defaultRatesPath()
+		if err != nil {
+			return nil, err
+		}
+		ratesPath = path
+	}
+	return NewConverter(to, ratesPath, known)
+}
+
+// convertingPrinter returns the printer converting the expenses to a currency with the rates of a file,
+// without a currency the printer is left as is
+func (s Switch) convertingPrinter(to, ratesPath string) (printer, error) {
+	c, err := converter(to, ratesPath, s.knownCurrencies)
+	if err != nil {
+		return printer{}, err
+	}
+	p := s.printer
+	p.converter = c
+	return p, nil
+}
+
+// defaultRatesPath returns the rates file of the config directory, rates.csv or else rates.json
+func defaultRatesPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "rates.csv")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	return filepath.Join(dir, "rates.json"), nil
+}
+
+// loadRates loads a csv file with a date,from,to,rate header, or a json array of objects with
+// the date, from, to and rate fields. Dates are formatted as YYYY-MM-DD and 1 from = rate to
+func loadRates(path string) (*rateTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open rates file")
+	}
+	defer f.Close()
+
+	var records [][]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		records, err = readJSONRates(f)
+	} else {
+		records, err = readCSVRates(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &rateTable{pairs: map[string][]rate{}}
+	seen := map[string]struct{}{}
+	for i, record := range records {
+		date, from, to := strings.TrimSpace(record[0]), strings.ToUpper(strings.TrimSpace(record[1])), strings.ToUpper(strings.TrimSpace(record[2]))
+		if _, err := time.Parse(rateDateLayout, date); err != nil {
+			return nil, errors.Errorf("invalid date '%s' of rate %d, expected YYYY-MM-DD", date, i+1)
+		}
+		for _, code := range []string{from, to} {
+			if err := (currency.Currency{Code: code}).Validate(); err != nil {
+				return nil, errors.Wrapf(err, "invalid currency of rate %d", i+1)
+			}
+		}
+		value, ok := new(big.Rat).SetString(strings.TrimSpace(record[3]))
+		if !ok || value.Sign() <= 0 {
+			return nil, errors.Errorf("invalid rate '%s' of rate %d, expected a positive number", record[3], i+1)
+		}
+
+		pair := from + "/" + to
+		t.pairs[pair] = append(t.pairs[pair], rate{date: date, value: value})
+		for _, code := range []string{from, to} {
+			if _, ok := seen[code]; !ok {
+				seen[code] = struct{}{}
+				t.currencies = append(t.currencies, code)
+			}
+		}
+	}
+	for _, rates := range t.pairs {
+		sort.SliceStable(rates, func(i, j int) bool {
+			return rates[i].date < rates[j].date
+		})
+	}
+	sort.Strings(t.currencies)
+	return t, nil
+}
+
+func readCSVRates(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read csv rates")
+	}
+	header := "date,from,to,rate"
+	if len(records) == 0 || strings.ToLower(strings.Join(records[0], ",")) != header {
+		return nil, errors.New("csv rates must start with the header: " + header)
+	}
+	return records[1:], nil
+}
+
+func readJSONRates(r io.Reader) ([][]string, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var rates []map[string]interface{}
+	if err := dec.Decode(&rates); err != nil {
+		return nil, errors.Wrap(err, "could not decode json rates")
+	}
+	records := make([][]string, 0, len(rates))
+	for _, rate := range rates {
+		record := make([]string, 4)
+		for i, field := range []string{"date", "from", "to", "rate"} {
+			if v, ok := rate[field]; ok && v != nil {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// lookup finds the rate of a currency pair as of a date, i.e. the latest rate dated on or before it.
+// Missing pairs are derived from the inverse pair, or else crossed through another currency
+func (t *rateTable) lookup(from, to string, at time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	date := "9999-12-31"
+	if !at.IsZero() {
+		date = at.UTC().Format(rateDateLayout)
+	}
+
+	if r, ok := t.pairRate(from, to, date); ok {
+		return r, nil
+	}
+	for _, via := range t.currencies {
+		if via == from || via == to {
+			continue
+		}
+		first, ok := t.pairRate(from, via, date)
+		if !ok {
+			continue
+		}
+		second, ok := t.pairRate(via, to, date)
+		if !ok {
+			continue
+		}
+		return new(big.Rat).Mul(first, second), nil
+	}
+	return nil, errors.Errorf("no %s/%s rate on or before %s", from, to, date)
+}
+
+// pairRate finds the rate of a currency pair or of its inverse as of a date
+func (t *rateTable) pairRate(from, to, date string) (*big.Rat, bool) {
+	if r, ok := rateAsOf(t.pairs[from+"/"+to], date); ok {
+		return r, true
+	}
+	if r, ok := rateAsOf(t.pairs[to+"/"+from], date); ok {
+		return new(big.Rat).Inv(r), true
+	}
+	return nil, false
+}
+
+// rateAsOf returns the latest of the rates sorted by date which is dated on or before a date
+func rateAsOf(rates []rate, date string) (*big.Rat, bool) {
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].date > date
+	})
+	if i == 0 {
+		return nil, false
+	}
+	return rates[i-1].value, true
+}
+
+// formatRate formats a rate with at most rateDecimals decimal places
+func formatRate(r *big.Rat) string {
+	s := r.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRates = `date,from,to,rate
+2020-01-01,EUR,USD,1.10
+2020-02-01,EUR,USD,1.20
+2020-01-01,GBP,EUR,1.25
+2020-01-15,usd,jpy,100
+`
+
+// writeRates writes a rates file to a temp directory and returns its path
+func writeRates(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRateTableLookup(t *testing.T) {
+	rates, err := loadRates(writeRates(t, "rates.csv", testRates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(s string) time.Time {
+		d, err := time.Parse(rateDateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d.Add(12 * time.Hour)
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		at      time.Time
+		want    string
+		wantErr bool
+	}{
+		{name: "same currency", from: "EUR", to: "EUR", at: day("2019-01-01"), want: "1"},
+		{name: "direct", from: "EUR", to: "USD", at: day("2020-01-20"), want: "11/10"},
+		{name: "on the rate date", from: "EUR", to: "USD", at: day("2020-02-01"), want: "6/5"},
+		{name: "latest rate", from: "EUR", to: "USD", at: day("2021-06-01"), want: "6/5"},
+		{name: "zero time uses the latest rate", from: "EUR", to: "USD", want: "6/5"},
+		{name: "inverse", from: "USD", to: "EUR", at: day("2020-01-20"), want: "10/11"},
+		{name: "cross", from: "GBP", to: "USD", at: day("2020-01-20"), want: "11/8"},
+		{name: "cross through inverse", from: "EUR", to: "JPY", at: day("2020-02-10"), want: "120"},
+		{name: "before the first rate", from: "EUR", to: "USD", at: day("2019-12-31"), wantErr: true},
+		{name: "cross before one of the rates", from: "EUR", to: "JPY", at: day("2020-01-10"), wantErr: true},
+		{name: "unknown pair", from: "CHF", to: "USD", at: day("2020-01-20"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.lookup(tt.from, tt.to, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got.RatString() != tt.want {
+				t.Errorf("got rate %s, want %s", got.RatString(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{name: "csv", file: "rates.csv", content: testRates},
+		{name: "json", file: "rates.json", content: `[{"date":"2020-01-01","from":"EUR","to":"USD","rate":1.1}]`},
+		{name: "missing header", file: "rates.csv", content: "2020-01-01,EUR,USD,1.1\n", wantErr: true},
+		{name: "invalid date", file: "rates.csv", content: "date,from,to,rate\n01/01/2020,EUR,USD,1.1\n", wantErr: true},
+		{name: "invalid currency", file: "rates.csv", content: "date,from,to,rate\n2020-01-01,EURO,USD,1.1\n", wantErr: true},
+		{name: "zero rate", file: "rates.csv", content: "date,from,to,rate\n2020-01-01,EUR,USD,0\n", wantErr: true},
+		{name: "missing json field", file: "rates.json", content: `[{"date":"2020-01-01","from":"EUR","rate":1.1}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadRates(writeRates(t, tt.file, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConverter(t *testing.T) {
+	c, err := NewConverter("usd", writeRates(t, "rates.csv", testRates), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC)
+	expenses := []Expense{
+		{ID: "e1", Price: testMoney(1000, "EUR"), CreatedAt: createdAt},
+		{ID: "e2", Price: testMoney(333, "GBP"), CreatedAt: createdAt},
+		{ID: "e3", Price: testMoney(250, "USD"), CreatedAt: createdAt},
+	}
+
+	converted, err := c.ConvertAll(expenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		amount int64
+		rate   string
+	}{
+		{amount: 1100, rate: "1.1"},
+		{amount: 458, rate: "1.375"},
+		{amount: 250, rate: "1"},
+	}
+	for i, ce := range converted.Expenses {
+		if ce.Converted.Amount != want[i].amount || ce.Converted.Currency != "USD" || ce.Rate != want[i].rate {
+			t.Errorf("expense %s: got %s at %s, want %d USD at %s", ce.ID, ce.Converted, ce.Rate, want[i].amount, want[i].rate)
+		}
+	}
+	if converted.Total != testMoney(1808, "USD") {
+		t.Errorf("got total %s, want 18.08 USD", converted.Total)
+	}
+
+	bs, err := json.Marshal(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(bs, []byte(`"converted_total":18.08,"converted_currency":"USD"`)) {
+		t.Errorf("got json %s, want the converted total", bs)
+	}
+
+	if _, err := c.Convert(Expense{ID: "e4", Price: testMoney(100, "CHF")}); err == nil {
+		t.Error("got no error, want an error for a currency without rates")
+	}
+}
+
+func TestExpensesWriterConvertedSummary(t *testing.T) {
+	c, err := NewConverter("USD", writeRates(t, "rates.csv", testRates), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenses := []Expense{
+		{ID: "e1", Price: testMoney(1000, "EUR"), CreatedAt: time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{ID: "e2", Price: testMoney(500, "USD")},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: JSONOutput, want: `],"converted_total":16.00,"converted_currency":"USD"}`},
+		{format: NDJSONOutput, want: `{"summary":{"count":2,"converted_total":16.00,"converted_currency":"USD"}}`},
+		{format: TableOutput, want: "total: 16.00 USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			p, err := newPrinter(tt.format, "", &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.converter = c
+			w := p.newExpensesWriter()
+			for _, e := range expenses {
+				if err := w.write(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(out.String()); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("got output %s, want it to end with %s", got, tt.want)
+			}
+			if tt.format == JSONOutput && !json.Valid(out.Bytes()) {
+				t.Errorf("got invalid json %s", out.String())
+			}
+		})
+	}
+}
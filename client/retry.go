@@ -0,0 +1,95 @@
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy represents the policy of retrying failed backend calls
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, values below 2 disable retries
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every following retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay, Retry-After values above it are not waited for
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used when none is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// attempts returns the maximum number of attempts for a given request.
+// Only idempotent requests, or requests carrying an idempotency key are retried
+func (p RetryPolicy) attempts(req *http.Request) int {
+	if p.MaxAttempts < 2 {
+		return 1
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return p.MaxAttempts
+	}
+	if req.Header.Get(idempotencyKeyHeader) != "" {
+		return p.MaxAttempts
+	}
+	return 1
+}
+
+// backoff returns the exponential backoff delay with jitter before a given retry (starting at 1)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << uint(retry-1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// delay returns how long to wait before retrying a failed call and whether it should be retried at all
+func (p RetryPolicy) delay(retry int, res *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		return p.backoff(retry), true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if d, ok := retryAfter(res); ok {
+			if p.MaxDelay > 0 && d > p.MaxDelay {
+				return 0, false
+			}
+			return d, true
+		}
+		return p.backoff(retry), true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return p.backoff(retry), true
+	}
+	return 0, false
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
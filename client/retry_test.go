@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyAttempts(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	tests := []struct {
+		name           string
+		policy         RetryPolicy
+		method         string
+		idempotencyKey string
+		want           int
+	}{
+		{name: "get", policy: policy, method: http.MethodGet, want: 3},
+		{name: "delete", policy: policy, method: http.MethodDelete, want: 3},
+		{name: "post", policy: policy, method: http.MethodPost, want: 1},
+		{name: "post with idempotency key", policy: policy, method: http.MethodPost, idempotencyKey: "key", want: 3},
+		{name: "patch with idempotency key", policy: policy, method: http.MethodPatch, idempotencyKey: "key", want: 3},
+		{name: "retries disabled", policy: RetryPolicy{MaxAttempts: 1}, method: http.MethodGet, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "http://localhost", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.idempotencyKey != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.idempotencyKey)
+			}
+			if got := tt.policy.attempts(req); got != tt.want {
+				t.Errorf("got %d attempts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	res := func(status int, retryAfter string) *http.Response {
+		r := &http.Response{StatusCode: status, Header: http.Header{}}
+		if retryAfter != "" {
+			r.Header.Set("Retry-After", retryAfter)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		retry     int
+		res       *http.Response
+		err       error
+		wantRetry bool
+		wantMin   time.Duration
+		wantMax   time.Duration
+	}{
+		{name: "network error", retry: 1, err: errors.New("connection reset"), wantRetry: true, wantMin: 50 * time.Millisecond, wantMax: 100 * time.Millisecond},
+		{name: "doubled backoff", retry: 3, res: res(http.StatusBadGateway, ""), wantRetry: true, wantMin: 200 * time.Millisecond, wantMax: 400 * time.Millisecond},
+		{name: "capped backoff", retry: 10, res: res(http.StatusInternalServerError, ""), wantRetry: true, wantMin: 500 * time.Millisecond, wantMax: time.Second},
+		{name: "retry after seconds", retry: 1, res: res(http.StatusTooManyRequests, "1"), wantRetry: true, wantMin: time.Second, wantMax: time.Second},
+		{name: "retry after above max delay", retry: 1, res: res(http.StatusServiceUnavailable, "30"), wantRetry: false},
+		{name: "unavailable without retry after", retry: 2, res: res(http.StatusServiceUnavailable, ""), wantRetry: true, wantMin: 100 * time.Millisecond, wantMax: 200 * time.Millisecond},
+		{name: "client error", retry: 1, res: res(http.StatusBadRequest, ""), wantRetry: false},
+		{name: "unauthorized", retry: 1, res: res(http.StatusUnauthorized, ""), wantRetry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got, retry := policy.delay(tt.retry, tt.res, tt.err)
+				if retry != tt.wantRetry {
+					t.Fatalf("got retry %v, want %v", retry, tt.wantRetry)
+				}
+				if retry && (got < tt.wantMin || got > tt.wantMax) {
+					t.Fatalf("got delay %s, want between %s and %s", got, tt.wantMin, tt.wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantOK  bool
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{name: "missing", value: "", wantOK: false},
+		{name: "seconds", value: "3", wantOK: true, wantMin: 3 * time.Second, wantMax: 3 * time.Second},
+		{name: "zero seconds", value: "0", wantOK: true},
+		{name: "negative seconds", value: "-1", wantOK: false},
+		{name: "http date", value: time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), wantOK: true, wantMin: 8 * time.Second, wantMax: 10 * time.Second},
+		{name: "past http date", value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), wantOK: true},
+		{name: "invalid", value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				res.Header.Set("Retry-After", tt.value)
+			}
+			got, ok := retryAfter(res)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("got %s, want between %s and %s", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
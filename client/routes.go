@@ -0,0 +1,59 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// route represents a backend API endpoint and the status codes it responds with on success
+type route struct {
+	method      string
+	path        string
+	statusCodes []int
+	// listParam fills in the single path placeholder with the comma separated params
+	listParam bool
+}
+
+// routes table of every backend API endpoint used by HTTPClient
+var (
+	getAllRoute     = route{http.MethodGet, "/expenses", []int{http.StatusOK}, false}
+	getByIDsRoute   = route{http.MethodGet, "/expenses/%s", []int{http.StatusOK}, true}
+	createRoute     = route{http.MethodPost, "/expenses", []int{http.StatusCreated, http.StatusOK}, false}
+	updateRoute     = route{http.MethodPatch, "/expenses/%s", []int{http.StatusOK, http.StatusNoContent}, false}
+	deleteRoute     = route{http.MethodDelete, "/expenses/%s", []int{http.StatusNoContent, http.StatusOK}, false}
+	loginRoute      = route{http.MethodPost, "/login", []int{http.StatusOK}, false}
+	signupRoute     = route{http.MethodPost, "/signup", []int{http.StatusCreated, http.StatusOK}, false}
+	logoutRoute     = route{http.MethodPost, "/logout", []int{http.StatusOK, http.StatusNoContent}, false}
+	refreshRoute    = route{http.MethodPost, "/refresh", []int{http.StatusOK}, false}
+	meRoute         = route{http.MethodGet, "/me", []int{http.StatusOK}, false}
+	currenciesRoute = route{http.MethodGet, "/currencies", []int{http.StatusOK}, false}
+)
+
+// url builds the route path by escaping the given params into its placeholders,
+// the params of a list route are escaped one by one, so that the commas separating them are not
+func (r route) url(params ...string) string {
+	escaped := make([]string, 0, len(params))
+	for _, p := range params {
+		escaped = append(escaped, url.PathEscape(p))
+	}
+	if r.listParam {
+		return fmt.Sprintf(r.path, strings.Join(escaped, ","))
+	}
+	args := make([]interface{}, 0, len(escaped))
+	for _, p := range escaped {
+		args = append(args, p)
+	}
+	return fmt.Sprintf(r.path, args...)
+}
+
+// accepts checks if the status code is one of the route success status codes
+func (r route) accepts(statusCode int) bool {
+	for _, code := range r.statusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
@@ -1,36 +1,45 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // signup represents the signup command which signs the user up and saves the access token to file
-func (s Switch) signup() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) signup() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		signupCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		email, pwd := setEmailFlag(signupCmd), setPasswordFlag(signupCmd)
+		pwdStdin := setPasswordStdinFlag(signupCmd)
 		if err := s.parseCmd(signupCmd); err != nil {
 			return err
 		}
-		if err := s.checkArgs(signupCmd, 2); err != nil {
+		if err := s.checkArgs(signupCmd, 1); err != nil {
+			return err
+		}
+		if email.value == "" {
+			return errors.New("email must be provided")
+		}
+		if err := promptPassword(pwd, *pwdStdin, true); err != nil {
 			return err
 		}
 
-		_, err := s.client.Signup(email.value, pwd.value)
+		credentials, err := s.client.Signup(ctx, email.value, pwd.value)
 		if err != nil {
 			return errors.Wrap(err, "could not sign up the user")
 		}
 
-		credentials := Credentials{AccessToken: "some-access-token"}
-		err = saveCredentials(credentials)
+		err = saveCredentials(s.profile.Name, credentials)
 		if err != nil {
 			return errors.Wrap(err, "could not save credentials to file")
 		}
 
-		fmt.Println("successfully signed up the user")
-		return nil
+		return s.printer.printResult(commandResult{
+			ID:      credentials.UserID,
+			Status:  "signed-up",
+			Message: "successfully signed up the user",
+		})
 	}
 }
@@ -1,62 +1,141 @@
 package client
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
+
+	"github.com/steevehook/expenses-cli/currency"
 )
 
 // BackendHTTPClient represents the HTTP client for communicating with the Backend API
 type BackendHTTPClient interface {
-	GetAll(page, pageSize string) ([]byte, error)
-	GetByIDs(ids ...string) ([]byte, error)
-	Create(title, currency string, price float64) error
-	Update(id, title, currency string, price float64) error
-	Delete(id string) error
-	Login(email, password string) ([]byte, error)
-	Logout() error
-	Signup(email, password string) ([]byte, error)
+	GetAll(ctx context.Context, query ExpensesQuery) (ExpensesPage, error)
+	GetByIDs(ctx context.Context, ids ...string) ([]Expense, error)
+	Create(ctx context.Context, title string, price Money, idempotencyKey string) (Expense, error)
+	Update(ctx context.Context, id, title string, price Money, idempotencyKey string) error
+	Delete(ctx context.Context, id string) error
+	Login(ctx context.Context, email, password string) (Credentials, error)
+	Logout(ctx context.Context) error
+	Signup(ctx context.Context, email, password string) (Credentials, error)
+	Me(ctx context.Context) (User, error)
+	Currencies(ctx context.Context) ([]currency.Currency, error)
+}
+
+// Options represents the global CLI options, which take precedence over the active profile settings
+type Options struct {
+	Profile    string
+	BackendURI string
+	Auth       AuthConfig
+	HTTP       HTTPClientConfig
+	// Output is the output format of the command results, Template is the Go template of the template format
+	Output   string
+	Template string
 }
 
-// NewSwitch creates a new instance of command Switch
-func NewSwitch(uri string) Switch {
-	httpClient := NewHTTPClient(uri)
-	s := Switch{client: httpClient, backendAPIURL: uri}
-	s.commands = map[string]func() func(string) error{
-		"get-all":    s.getAll,
-		"get-by-ids": s.getByIDs,
-		"create":     s.create,
-		"update":     s.update,
-		"delete":     s.delete,
-		"login":      s.login,
-		"logout":     s.logout,
-		"signup":     s.signup,
+// NewSwitch creates a new instance of command Switch.
+// The profile is resolved when a command needs it, so that a broken profile can still be switched away from
+func NewSwitch(opts Options) (Switch, error) {
+	p, err := newPrinter(opts.Output, opts.Template, os.Stdout)
+	if err != nil {
+		return Switch{}, errors.Wrap(err, "could not create output printer")
+	}
+	s := Switch{opts: opts, printer: p, registry: currency.ISO4217(), knownCurrencies: currency.ISO4217()}
+	s.commands = map[string]func(Switch) func(context.Context, string) error{
+		"get-all":    Switch.getAll,
+		"get-by-ids": Switch.getByIDs,
+		"create":     Switch.create,
+		"update":     Switch.update,
+		"delete":     Switch.delete,
+		"login":      Switch.login,
+		"logout":     Switch.logout,
+		"signup":     Switch.signup,
+		"profile":    Switch.profileCmd,
+		"import":     Switch.importCmd,
+		"export":     Switch.export,
+		"currencies": Switch.currencies,
+		"whoami":     Switch.whoami,
 	}
-	return s
+	return s, nil
 }
 
 // Switch represents CLI command switch
 type Switch struct {
+	opts          Options
 	client        BackendHTTPClient
 	backendAPIURL string
-	commands      map[string]func() func(string) error
+	profile       Profile
+	printer       printer
+	commands      map[string]func(Switch) func(context.Context, string) error
+	// registry holds the currencies accepted by the currency flags of the commands
+	registry *currency.Registry
+	// knownCurrencies holds the decimal places of every currency an amount may be in, it is never restricted
+	knownCurrencies *currency.Registry
 }
 
-// Switch analyses the CLI args and executes the given command
-func (s Switch) Switch() error {
-	cmdName := os.Args[1]
-	cmd, ok := s.commands[os.Args[1]]
+// Switch analyses the CLI args and executes the given command,
+// cancelling the context aborts all the in-flight backend calls
+func (s Switch) Switch(ctx context.Context) error {
+	cmdName := flag.Arg(0)
+	cmd, ok := s.commands[cmdName]
 	if !ok {
 		return fmt.Errorf("invalid command '%s'", cmdName)
 	}
-	return cmd()(cmdName)
+	if cmdName != "profile" {
+		if err := s.configure(); err != nil {
+			return err
+		}
+	}
+	if s.profile.Currencies.FromBackend && currencyCommands[cmdName] {
+		if err := loadBackendCurrencies(ctx, s.client, s.registry, s.knownCurrencies); err != nil {
+			return err
+		}
+	}
+	return cmd(s)(ctx, cmdName)
+}
+
+// configure resolves the profile and creates the backend client of the commands
+func (s *Switch) configure() error {
+	profile, err := resolveProfile(s.opts)
+	if err != nil {
+		return errors.Wrap(err, "could not resolve profile")
+	}
+	if profile.Name == DefaultProfileName {
+		if err := migrateLegacyCredentials(); err != nil {
+			return err
+		}
+	}
+	auth, err := NewAuthScheme(profile.Name, profile.Auth)
+	if err != nil {
+		return errors.Wrap(err, "could not create auth scheme")
+	}
+	registry, err := newCurrencyRegistry(profile.Currencies)
+	if err != nil {
+		return errors.Wrap(err, "could not configure currencies")
+	}
+	known, err := newKnownCurrencies(profile.Currencies)
+	if err != nil {
+		return errors.Wrap(err, "could not configure currencies")
+	}
+	httpConfig := s.opts.HTTP
+	httpConfig.TLS = profile.TLS
+	httpConfig.Currencies = known
+	httpClient, err := NewHTTPClient(profile.BackendURI, auth, httpConfig)
+	if err != nil {
+		return errors.Wrap(err, "could not create http client")
+	}
+
+	s.client, s.backendAPIURL, s.profile = httpClient, profile.BackendURI, profile
+	s.registry, s.knownCurrencies = registry, known
+	return nil
 }
 
 // parseCmd parses sub-command flags
 func (s Switch) parseCmd(cmd *flag.FlagSet) error {
-	err := cmd.Parse(os.Args[2:])
+	err := cmd.Parse(flag.Args()[1:])
 	if err != nil {
 		return errors.Wrap(err, "could not parse '"+cmd.Name()+"' flags")
 	}
@@ -66,9 +145,10 @@ func (s Switch) parseCmd(cmd *flag.FlagSet) error {
 // checkArgs checks if the number of passed args for a command is greater or equal to min args
 func (s Switch) checkArgs(cmd *flag.FlagSet, minArgs int) error {
 	if cmd.NFlag() < minArgs {
-		fmt.Printf(
+		fmt.Fprintf(
+			os.Stderr,
 			"incorect use of %s\n%s %s --help\n",
-			os.Args[1], os.Args[0], os.Args[1],
+			cmd.Name(), os.Args[0], cmd.Name(),
 		)
 		return fmt.Errorf(
 			"%s expects at least: %d arg(s), %d provided",
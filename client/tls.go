@@ -0,0 +1,99 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig represents the TLS settings used to connect to https backends
+type TLSConfig struct {
+	// CACert is the path of a PEM CA bundle trusted in addition to the system roots
+	CACert string `json:"ca_cert,omitempty"`
+	// ClientCert and ClientKey are the paths of the PEM client certificate and key used for mTLS
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// MinVersion is the minimum accepted TLS version: 1.0, 1.1, 1.2 or 1.3
+	MinVersion string `json:"min_version,omitempty"`
+	// Insecure skips the verification of the backend certificate, meant for local self-signed backends only.
+	// It is unset when not configured, so that the command line can turn it on or off for a profile
+	Insecure *bool `json:"insecure,omitempty"`
+}
+
+// merge returns the TLS settings with the non empty settings of other applied on top of them
+func (c TLSConfig) merge(other TLSConfig) TLSConfig {
+	if other.CACert != "" {
+		c.CACert = other.CACert
+	}
+	if other.ClientCert != "" {
+		c.ClientCert = other.ClientCert
+	}
+	if other.ClientKey != "" {
+		c.ClientKey = other.ClientKey
+	}
+	if other.MinVersion != "" {
+		c.MinVersion = other.MinVersion
+	}
+	if other.Insecure != nil {
+		c.Insecure = other.Insecure
+	}
+	return c
+}
+
+// build creates the crypto/tls config described by the TLS settings
+func (c TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, errors.New("min TLS version must be one of: 1.0,1.1,1.2,1.3")
+		}
+		config.MinVersion = v
+	}
+
+	if c.CACert != "" {
+		pem, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read CA certificate")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no valid PEM certificates found in CA certificate")
+		}
+		config.RootCAs = pool
+	}
+
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return nil, errors.New("client certificate and client key must be provided together")
+	}
+	if c.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	config.InsecureSkipVerify = c.insecure()
+	return config, nil
+}
+
+// insecure reports whether the verification of the backend certificate is disabled
+func (c TLSConfig) insecure() bool {
+	return c.Insecure != nil && *c.Insecure
+}
@@ -0,0 +1,35 @@
+package client
+
+import "testing"
+
+func TestTLSConfigMergeInsecure(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		profile  *bool
+		override *bool
+		want     bool
+	}{
+		{name: "unset", want: false},
+		{name: "profile", profile: &on, want: true},
+		{name: "flag", override: &on, want: true},
+		{name: "flag turns off the profile setting", profile: &on, override: &off, want: false},
+		{name: "profile turned off", profile: &off, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := TLSConfig{Insecure: tt.profile}.merge(TLSConfig{Insecure: tt.override})
+			if got := merged.insecure(); got != tt.want {
+				t.Errorf("got insecure %v, want %v", got, tt.want)
+			}
+			config, err := merged.build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.InsecureSkipVerify != tt.want {
+				t.Errorf("got InsecureSkipVerify %v, want %v", config.InsecureSkipVerify, tt.want)
+			}
+		})
+	}
+}
@@ -1,20 +1,21 @@
 package client
 
 import (
+	"context"
 	"flag"
-	"fmt"
 
 	"github.com/pkg/errors"
 )
 
 // update represents the update command which updates an existing expense
-func (s Switch) update() func(string) error {
-	return func(cmdName string) error {
+func (s Switch) update() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
 		updateCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
 		t := setTitleFlag(updateCmd, true)
-		c := setCurrencyFlag(updateCmd, true)
+		c := setCurrencyFlag(updateCmd, s.registry, true)
 		p := setPriceFlag(updateCmd, true)
 		ids := setIDsFlag(updateCmd)
+		key := setIdempotencyKeyFlag(updateCmd)
 
 		if err := s.parseCmd(updateCmd); err != nil {
 			return err
@@ -26,12 +27,32 @@ func (s Switch) update() func(string) error {
 			return errors.New("id of the expense must be provided")
 		}
 
-		err := s.client.Update(ids.value[0], t.value, c.value, p.value)
+		currency := c.value
+		if p.set && currency == "" {
+			// the decimal places of the new price depend on the currency of the expense
+			expenses, err := s.client.GetByIDs(ctx, ids.value[0])
+			if err != nil {
+				return errors.Wrap(err, "could not fetch expense")
+			}
+			if len(expenses) == 0 {
+				return errors.Errorf("expense with id: %s does not exist", ids.value[0])
+			}
+			currency = expenses[0].Currency()
+		}
+		price, err := p.money(knownCurrency(s.knownCurrencies, currency))
+		if err != nil {
+			return err
+		}
+
+		err = s.client.Update(ctx, ids.value[0], t.value, price, key.value)
 		if err != nil {
 			return errors.Wrap(err, "could not update expense")
 		}
 
-		fmt.Println("expense updated successfully")
-		return nil
+		return s.printer.printResult(commandResult{
+			ID:      ids.value[0],
+			Status:  "updated",
+			Message: "expense updated successfully",
+		})
 	}
 }
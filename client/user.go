@@ -0,0 +1,10 @@
+package client
+
+import "time"
+
+// User represents the logged in user as returned by the backend API
+type User struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verbosity levels of the HTTP traffic logging
+const (
+	// VerbosityQuiet logs nothing
+	VerbosityQuiet = iota
+	// VerbosityInfo logs the method, URL, status and latency of every request
+	VerbosityInfo
+	// VerbosityDump additionally dumps the redacted headers and bodies of every request and response
+	VerbosityDump
+	// VerbosityTrace additionally logs the DNS, connect, TLS and first byte timings
+	VerbosityTrace
+)
+
+const redacted = "***"
+
+var (
+	sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", defaultAPIKeyHeader}
+	sensitiveFields  = regexp.MustCompile(`("(?:password|access_token|refresh_token|api_key)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// loggingTransport logs the HTTP traffic going through the next round tripper
+type loggingTransport struct {
+	next             http.RoundTripper
+	out              io.Writer
+	verbosity        int
+	sensitiveHeaders []string
+}
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timings := &connTimings{}
+	if t.verbosity >= VerbosityTrace {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), timings.clientTrace()))
+	}
+	if t.verbosity >= VerbosityDump {
+		// the request is dumped through a clone with redacted headers, the dump buffers the body
+		// of the clone which is then sent with the original headers, leaving the caller request untouched
+		dumped := req.Clone(req.Context())
+		dumped.Header = t.redactHeaders(req.Header)
+		t.dump(">", func() ([]byte, error) { return httputil.DumpRequestOut(dumped, true) })
+		dumped.Header = req.Header
+		req = dumped
+	}
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	latency := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(t.out, "%s %s failed after %s: %v\n", req.Method, req.URL, latency.Round(time.Millisecond), err)
+		return nil, err
+	}
+	fmt.Fprintf(t.out, "%s %s %d %s\n", req.Method, req.URL, res.StatusCode, latency.Round(time.Millisecond))
+
+	if t.verbosity >= VerbosityDump {
+		dumped := *res
+		dumped.Header = t.redactHeaders(res.Header)
+		t.dump("<", func() ([]byte, error) { return httputil.DumpResponse(&dumped, true) })
+		res.Body = dumped.Body
+	}
+	if t.verbosity >= VerbosityTrace {
+		timings.print(t.out, start)
+	}
+	return res, nil
+}
+
+// redactHeaders returns a copy of the headers with the sensitive values masked
+func (t loggingTransport) redactHeaders(header http.Header) http.Header {
+	redactedHeader := header.Clone()
+	for _, name := range t.sensitiveHeaders {
+		if redactedHeader.Get(name) != "" {
+			redactedHeader.Set(name, redactHeader(redactedHeader.Get(name)))
+		}
+	}
+	return redactedHeader
+}
+
+// dump writes the redacted dump of a request or response, every line prefixed with the direction
+func (t loggingTransport) dump(direction string, dumpFn func() ([]byte, error)) {
+	bs, err := dumpFn()
+	if err != nil {
+		fmt.Fprintf(t.out, "%s could not dump: %v\n", direction, err)
+		return
+	}
+
+	bs = sensitiveFields.ReplaceAll(bs, []byte(`$1"`+redacted+`"`))
+	for _, line := range strings.Split(strings.ReplaceAll(string(bs), "\r\n", "\n"), "\n") {
+		fmt.Fprintf(t.out, "%s %s\n", direction, line)
+	}
+}
+
+// redactHeader masks a header value, keeping the auth scheme (e.g. Bearer) visible
+func redactHeader(value string) string {
+	for _, scheme := range []string{"Bearer ", "Basic "} {
+		if len(value) > len(scheme) && strings.HasPrefix(value, scheme) {
+			return scheme + redacted
+		}
+	}
+	return redacted
+}
+
+// connTimings records the connection timings of a request. The trace hooks may run concurrently,
+// e.g. when dialing both IPv4 and IPv6 addresses, so the timings are guarded by a mutex
+type connTimings struct {
+	mu                        sync.Mutex
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+	reused                    bool
+}
+
+func (c *connTimings) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { c.record(&c.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { c.record(&c.dnsDone) },
+		// with several dial attempts, the connect span goes from the first attempt to the first successful one
+		ConnectStart: func(string, string) { c.record(&c.connectStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				c.record(&c.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { c.record(&c.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { c.record(&c.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.reused = info.Reused
+		},
+		GotFirstResponseByte: func() { c.record(&c.firstByte) },
+	}
+}
+
+// record sets a timing to the current time unless it was already recorded
+func (c *connTimings) record(t *time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// print writes the recorded timings relative to the start of the request
+func (c *connTimings) print(out io.Writer, start time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	span := func(from, to time.Time) string {
+		if from.IsZero() || to.IsZero() {
+			return "-"
+		}
+		return to.Sub(from).Round(time.Microsecond).String()
+	}
+	fmt.Fprintf(
+		out,
+		"* dns: %s, connect: %s, tls: %s, first byte: %s, reused connection: %t\n",
+		span(c.dnsStart, c.dnsDone),
+		span(c.connectStart, c.connectDone),
+		span(c.tlsStart, c.tlsDone),
+		span(start, c.firstByte),
+		c.reused,
+	)
+}
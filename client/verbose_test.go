@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// roundTripperFunc adapts a func to an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestLoggingTransportRedactsSecrets(t *testing.T) {
+	secrets := []string{"access-secret", "api-key-secret", "password-secret", "refresh-secret", "set-cookie-secret"}
+	reqBody := `{"email":"a@b.co","password":"password-secret","refresh_token":"refresh-secret"}`
+	resBody := `{"access_token":"access-secret","refresh_token":"refresh-secret","user_id":"u1"}`
+
+	var sent *http.Request
+	var sentBody string
+	next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		bs, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		sentBody = string(bs)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header: http.Header{
+				"Content-Type": []string{"application/json"},
+				"Set-Cookie":   []string{"session=set-cookie-secret"},
+			},
+			Body:    ioutil.NopCloser(strings.NewReader(resBody)),
+			Request: req,
+		}, nil
+	})
+
+	for _, verbosity := range []int{VerbosityInfo, VerbosityDump, VerbosityTrace} {
+		var out bytes.Buffer
+		transport := loggingTransport{
+			next:             next,
+			out:              &out,
+			verbosity:        verbosity,
+			sensitiveHeaders: append(append([]string{}, sensitiveHeaders...), "X-Custom-Key"),
+		}
+		req, err := http.NewRequest(http.MethodPost, "http://localhost/login", strings.NewReader(reqBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer access-secret")
+		req.Header.Set("X-Custom-Key", "api-key-secret")
+
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotResBody, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, secret := range secrets {
+			if strings.Contains(out.String(), secret) {
+				t.Errorf("verbosity %d: got %s in the output:\n%s", verbosity, secret, out.String())
+			}
+		}
+		if verbosity >= VerbosityDump && !strings.Contains(out.String(), "Bearer "+redacted) {
+			t.Errorf("verbosity %d: got no redacted bearer token in the output:\n%s", verbosity, out.String())
+		}
+		if req.Header.Get("Authorization") != "Bearer access-secret" || req.Header.Get("X-Custom-Key") != "api-key-secret" {
+			t.Errorf("verbosity %d: got caller headers modified: %v", verbosity, req.Header)
+		}
+		if sent.Header.Get("Authorization") != "Bearer access-secret" || sent.Header.Get("X-Custom-Key") != "api-key-secret" {
+			t.Errorf("verbosity %d: got sent headers %v, want the original secrets", verbosity, sent.Header)
+		}
+		if sentBody != reqBody {
+			t.Errorf("verbosity %d: got sent body %s, want %s", verbosity, sentBody, reqBody)
+		}
+		if string(gotResBody) != resBody {
+			t.Errorf("verbosity %d: got response body %s, want %s", verbosity, gotResBody, resBody)
+		}
+		if res.Header.Get("Set-Cookie") != "session=set-cookie-secret" {
+			t.Errorf("verbosity %d: got response headers modified: %v", verbosity, res.Header)
+		}
+	}
+}
+
+func TestLoggingTransportTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	c := http.Client{Transport: loggingTransport{next: http.DefaultTransport, out: &out, verbosity: VerbosityTrace}}
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if got := strings.Count(out.String(), "* dns:"); got != 2 {
+		t.Errorf("got %d timing lines, want 2:\n%s", got, out.String())
+	}
+	if !strings.Contains(out.String(), "reused connection: true") {
+		t.Errorf("got no reused connection:\n%s", out.String())
+	}
+}
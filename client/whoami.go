@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotLoggedIn is returned when there are no valid credentials for the active profile
+var ErrNotLoggedIn = errors.New("not logged in")
+
+// jwtClaims represents the subset of JWT claims displayed by whoami
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// sessionStatus represents the session status of a profile as displayed by whoami
+type sessionStatus struct {
+	Profile    string `json:"profile"`
+	Backend    string `json:"backend"`
+	AuthScheme string `json:"auth_scheme"`
+	LoggedIn   bool   `json:"logged_in"`
+	Subject    string `json:"subject,omitempty"`
+	Email      string `json:"email,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	// VerifiedAs is the user returned by the backend when the session is verified remotely
+	VerifiedAs *User `json:"verified_as,omitempty"`
+
+	expiresAt time.Time
+}
+
+// whoami represents the whoami command which displays the session status of the active profile
+func (s Switch) whoami() func(context.Context, string) error {
+	return func(ctx context.Context, cmdName string) error {
+		whoamiCmd := flag.NewFlagSet(cmdName, flag.ExitOnError)
+		remote := whoamiCmd.Bool("remote", false, "Verify the session against the backend /me endpoint")
+		if err := s.parseCmd(whoamiCmd); err != nil {
+			return err
+		}
+
+		status := sessionStatus{
+			Profile:    s.profile.Name,
+			Backend:    s.backendAPIURL,
+			AuthScheme: s.profile.Auth.Scheme,
+		}
+		if status.AuthScheme == "" {
+			status.AuthScheme = BearerAuthScheme
+		}
+		err := s.checkSession(ctx, &status, *remote)
+		if printErr := s.printSessionStatus(status); printErr != nil {
+			return printErr
+		}
+		return err
+	}
+}
+
+// checkSession fills in the session status from the stored credentials and verifies them remotely if asked to,
+// the api-key and basic schemes have no stored credentials so only the remote check applies to them
+func (s Switch) checkSession(ctx context.Context, status *sessionStatus, remote bool) error {
+	status.LoggedIn = true
+	if status.AuthScheme == BearerAuthScheme {
+		if err := s.checkCredentials(status, remote); err != nil {
+			return err
+		}
+	}
+
+	if remote {
+		user, err := s.client.Me(ctx)
+		if err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.IsUnauthorized() {
+				status.LoggedIn = false
+			}
+			return errors.Wrap(err, "could not verify session")
+		}
+		status.VerifiedAs = &user
+	}
+	return nil
+}
+
+// checkCredentials fills in the session status from the stored bearer credentials and their JWT claims
+func (s Switch) checkCredentials(status *sessionStatus, remote bool) error {
+	credentials, err := readCredentials(s.profile.Name)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && credentials.AccessToken == "") {
+		status.LoggedIn = false
+		return ErrNotLoggedIn
+	}
+	if err != nil {
+		status.LoggedIn = false
+		return errors.Wrap(err, "could not read credentials")
+	}
+
+	status.expiresAt = credentials.ExpiresAt
+	if claims, ok := decodeJWTClaims(credentials.AccessToken); ok {
+		status.Subject, status.Email = claims.Subject, claims.Email
+		if claims.ExpiresAt != 0 {
+			status.expiresAt = time.Unix(claims.ExpiresAt, 0)
+		}
+	}
+	status.UserID = credentials.UserID
+	status.ExpiresAt = formatTime(status.expiresAt)
+	if !status.expiresAt.IsZero() && time.Now().After(status.expiresAt) && credentials.RefreshToken == "" && !remote {
+		status.LoggedIn = false
+		return errors.Wrap(ErrNotLoggedIn, "session expired")
+	}
+	return nil
+}
+
+// printSessionStatus prints the session status, the table format prints only the known fields
+func (s Switch) printSessionStatus(status sessionStatus) error {
+	switch s.printer.format {
+	case TableOutput:
+		out := s.printer.out
+		fmt.Fprintf(out, "profile: %s\nbackend: %s\n", status.Profile, status.Backend)
+		if status.AuthScheme != BearerAuthScheme {
+			fmt.Fprintf(out, "auth scheme: %s\n", status.AuthScheme)
+		}
+		if status.Subject != "" {
+			fmt.Fprintf(out, "subject: %s\n", status.Subject)
+		}
+		if status.Email != "" {
+			fmt.Fprintf(out, "email: %s\n", status.Email)
+		}
+		if status.UserID != "" {
+			fmt.Fprintf(out, "user id: %s\n", status.UserID)
+		}
+		if !status.expiresAt.IsZero() {
+			fmt.Fprintf(out, "expires at: %s (%s)\n", status.ExpiresAt, untilExpiry(status.expiresAt))
+		}
+		if status.VerifiedAs != nil {
+			fmt.Fprintf(out, "verified as: %s (id: %s)\n", status.VerifiedAs.Email, status.VerifiedAs.ID)
+		}
+		return nil
+	case CSVOutput:
+		w := csv.NewWriter(s.printer.out)
+		_ = w.Write([]string{"profile", "backend", "auth_scheme", "logged_in", "subject", "email", "user_id", "expires_at"})
+		_ = w.Write([]string{
+			status.Profile,
+			status.Backend,
+			status.AuthScheme,
+			strconv.FormatBool(status.LoggedIn),
+			status.Subject,
+			status.Email,
+			status.UserID,
+			status.ExpiresAt,
+		})
+		w.Flush()
+		return errors.Wrap(w.Error(), "could not write csv")
+	default:
+		return s.printer.printValue(status)
+	}
+}
+
+// decodeJWTClaims decodes the claims of a JWT access token without verifying its signature
+func decodeJWTClaims(token string) (jwtClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtClaims{}, false
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return jwtClaims{}, false
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(bs, &claims); err != nil {
+		return jwtClaims{}, false
+	}
+	return claims, true
+}
+
+// untilExpiry describes the time left until the given expiry
+func untilExpiry(expiresAt time.Time) string {
+	d := time.Until(expiresAt).Round(time.Second)
+	if d <= 0 {
+		return fmt.Sprintf("expired %s ago", -d)
+	}
+	return fmt.Sprintf("expires in %s", d)
+}
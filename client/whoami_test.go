@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckSessionRemote(t *testing.T) {
+	tests := []struct {
+		name         string
+		auth         AuthConfig
+		remote       bool
+		status       int
+		wantCalls    int
+		wantLoggedIn bool
+		wantVerified bool
+		wantErr      bool
+	}{
+		{name: "api key without remote", auth: AuthConfig{Scheme: APIKeyAuthScheme, APIKey: "key"}, wantLoggedIn: true},
+		{name: "api key verified", auth: AuthConfig{Scheme: APIKeyAuthScheme, APIKey: "key"}, remote: true, status: http.StatusOK, wantCalls: 1, wantLoggedIn: true, wantVerified: true},
+		{name: "api key rejected", auth: AuthConfig{Scheme: APIKeyAuthScheme, APIKey: "key"}, remote: true, status: http.StatusUnauthorized, wantCalls: 1, wantErr: true},
+		{name: "basic rejected", auth: AuthConfig{Scheme: BasicAuthScheme, Username: "u", Password: "p"}, remote: true, status: http.StatusUnauthorized, wantCalls: 1, wantErr: true},
+		{name: "bearer without credentials", auth: AuthConfig{Scheme: BearerAuthScheme}, remote: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfigHome(t)
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				if tt.status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"id":"u1","email":"a@b.co"}`))
+				}
+			}))
+			defer srv.Close()
+
+			auth, err := NewAuthScheme(DefaultProfileName, tt.auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := NewHTTPClient(srv.URL, auth, HTTPClientConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := Switch{client: c, profile: Profile{Name: DefaultProfileName, Auth: tt.auth}}
+			status := sessionStatus{AuthScheme: tt.auth.Scheme}
+
+			err = s.checkSession(context.Background(), &status, tt.remote)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d /me calls, want %d", calls, tt.wantCalls)
+			}
+			if status.LoggedIn != tt.wantLoggedIn {
+				t.Errorf("got logged in %v, want %v", status.LoggedIn, tt.wantLoggedIn)
+			}
+			if (status.VerifiedAs != nil) != tt.wantVerified {
+				t.Errorf("got verified as %+v, want verified %v", status.VerifiedAs, tt.wantVerified)
+			}
+		})
+	}
+}
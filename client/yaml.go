@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// orderedMap represents a json object which preserves the order of its keys
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// marshalYAML encodes v as YAML by going through its json representation,
+// so the json tags and field order of the types are reused
+func marshalYAML(v interface{}) ([]byte, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal json")
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode json")
+	}
+
+	var b strings.Builder
+	switch n := node.(type) {
+	case orderedMap, []interface{}:
+		if isEmptyYAMLCollection(n) {
+			b.WriteString(yamlScalar(n) + "\n")
+		} else {
+			writeYAML(&b, n, 0)
+		}
+	default:
+		b.WriteString(yamlScalar(n) + "\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// decodeOrdered decodes the next json value into an orderedMap, a slice or a scalar
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := t.(json.Delim)
+	if !ok {
+		return t, nil
+	}
+	switch delim {
+	case '{':
+		m := orderedMap{values: map[string]interface{}{}}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := kt.(string)
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.keys = append(m.keys, key)
+			m.values[key] = v
+		}
+		_, err := dec.Token()
+		return m, err
+	case '[':
+		list := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// writeYAML writes a non empty map or list as a YAML block at the given indent
+func writeYAML(b *strings.Builder, node interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch n := node.(type) {
+	case orderedMap:
+		for _, key := range n.keys {
+			v := n.values[key]
+			b.WriteString(pad + yamlKey(key) + ":")
+			writeYAMLChild(b, v, indent+2)
+		}
+	case []interface{}:
+		for _, v := range n {
+			if m, ok := v.(orderedMap); ok && !isEmptyYAMLCollection(m) {
+				// the first key of a map item goes on the same line as the dash
+				var item strings.Builder
+				writeYAML(&item, m, indent+2)
+				b.WriteString(pad + "- " + strings.TrimPrefix(item.String(), pad+"  "))
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLChild(b, v, indent+2)
+		}
+	}
+}
+
+// writeYAMLChild writes a value following a key or a dash
+func writeYAMLChild(b *strings.Builder, v interface{}, indent int) {
+	switch v.(type) {
+	case orderedMap, []interface{}:
+		if !isEmptyYAMLCollection(v) {
+			b.WriteString("\n")
+			writeYAML(b, v, indent)
+			return
+		}
+	}
+	b.WriteString(" " + yamlScalar(v) + "\n")
+}
+
+func isEmptyYAMLCollection(v interface{}) bool {
+	switch n := v.(type) {
+	case orderedMap:
+		return len(n.keys) == 0
+	case []interface{}:
+		return len(n) == 0
+	}
+	return false
+}
+
+func yamlKey(key string) string {
+	for _, c := range key {
+		if !(c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return strconv.Quote(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+func yamlScalar(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(s)
+	case json.Number:
+		return s.String()
+	case string:
+		return strconv.Quote(s)
+	case orderedMap:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return ""
+}
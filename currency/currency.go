@@ -0,0 +1,131 @@
+// Package currency provides a registry of the currencies accepted by the CLI,
+// seeded with the ISO 4217 list and optionally extended or restricted by configuration
+package currency
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// maxMinorUnits is the maximum number of decimal places of a currency
+const maxMinorUnits = 8
+
+// Currency represents a currency with its ISO 4217 code, number of decimal places and display names
+type Currency struct {
+	Code       string `json:"code"`
+	Numeric    string `json:"numeric,omitempty"`
+	MinorUnits int    `json:"minor_units"`
+	Name       string `json:"name"`
+	Symbol     string `json:"symbol,omitempty"`
+}
+
+// Validate checks that the currency has a three letter code and a valid number of decimal places
+func (c Currency) Validate() error {
+	if !isCode(c.Code) {
+		return errors.Errorf("currency code '%s' must be three upper case letters", c.Code)
+	}
+	if c.MinorUnits < 0 || c.MinorUnits > maxMinorUnits {
+		return errors.Errorf("minor units of currency '%s' must be between 0 and %d", c.Code, maxMinorUnits)
+	}
+	return nil
+}
+
+// Registry represents a set of currencies indexed by code, it is safe for concurrent use
+type Registry struct {
+	mu         sync.RWMutex
+	currencies map[string]Currency
+}
+
+// New creates a registry of the given currencies
+func New(currencies ...Currency) (*Registry, error) {
+	r := &Registry{currencies: map[string]Currency{}}
+	if err := r.Add(currencies...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ISO4217 creates a registry of the active ISO 4217 currencies
+func ISO4217() *Registry {
+	r := &Registry{currencies: make(map[string]Currency, len(iso4217))}
+	for _, c := range iso4217 {
+		r.currencies[c.Code] = c
+	}
+	return r
+}
+
+// Lookup finds a currency by its case insensitive code
+func (r *Registry) Lookup(code string) (Currency, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// All returns every currency of the registry sorted by code
+func (r *Registry) All() []Currency {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	currencies := make([]Currency, 0, len(r.currencies))
+	for _, c := range r.currencies {
+		currencies = append(currencies, c)
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+	return currencies
+}
+
+// Add adds currencies to the registry, replacing the existing currencies with the same code.
+// The codes are normalised on a copy, so the given currencies are left untouched
+func (r *Registry) Add(currencies ...Currency) error {
+	added := make([]Currency, len(currencies))
+	for i, c := range currencies {
+		c.Code = strings.ToUpper(strings.TrimSpace(c.Code))
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		added[i] = c
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range added {
+		r.currencies[c.Code] = c
+	}
+	return nil
+}
+
+// Restrict removes every currency but the ones of the given codes, which must be part of the registry
+func (r *Registry) Restrict(codes ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	restricted := make(map[string]Currency, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		c, ok := r.currencies[code]
+		if !ok {
+			return errors.Errorf("currency '%s' is unknown", code)
+		}
+		restricted[code] = c
+	}
+	r.currencies = restricted
+	return nil
+}
+
+// isCode checks if a code is made of three upper case letters
+func isCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -5,4 +5,5 @@ go 1.15
 require (
 	github.com/google/uuid v1.1.2
 	github.com/pkg/errors v0.9.1
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
 )
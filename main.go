@@ -1,30 +1,162 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
 
 	"github.com/steevehook/expenses-cli/client"
 )
 
 var (
-	backendURIFlag = flag.String("backend", "http://localhost:8080", "Expenses REST API URL")
-	helpFlag       = flag.Bool("help", false, "Display a helpful message")
+	profileFlag        = flag.String("profile", "", "Name of the backend profile to use (defaults to the active profile)")
+	backendURIFlag     = flag.String("backend", "", "Expenses REST API URL (overrides the profile backend)")
+	authSchemeFlag     = flag.String("auth", "", "Auth scheme: bearer, api-key or basic (overrides the profile auth scheme)")
+	apiKeyHeaderFlag   = flag.String("api-key-header", "X-API-Key", "Header used by the api-key auth scheme")
+	apiKeyFlag         = flag.String("api-key", "", "API key used by the api-key auth scheme (visible in the process list, prefer --api-key-stdin or "+client.APIKeyEnv+")")
+	apiKeyStdinFlag    = flag.Bool("api-key-stdin", false, "Read the API key used by the api-key auth scheme from the first line of stdin")
+	basicUserFlag      = flag.String("basic-user", "", "Username used by the basic auth scheme")
+	basicPassFlag      = flag.String("basic-password", "", "Password used by the basic auth scheme (visible in the process list, prefer --basic-password-stdin or "+client.BasicPasswordEnv+")")
+	basicPassStdinFlag = flag.Bool("basic-password-stdin", false, "Read the password used by the basic auth scheme from the first line of stdin")
+	timeoutFlag        = flag.Duration("timeout", 30*time.Second, "Timeout of a single backend call, 0 means no timeout")
+	retriesFlag        = flag.Int("retries", client.DefaultRetryPolicy.MaxAttempts, "Maximum attempts of a failed backend call, 1 disables retries")
+	retryDelayFlag     = flag.Duration("retry-delay", client.DefaultRetryPolicy.BaseDelay, "Initial delay between retries, doubled on every retry")
+	caCertFlag         = flag.String("ca-cert", "", "Path of a PEM CA bundle used to verify the backend")
+	clientCertFlag     = flag.String("client-cert", "", "Path of the PEM client certificate used for mTLS")
+	clientKeyFlag      = flag.String("client-key", "", "Path of the PEM client key used for mTLS")
+	tlsMinFlag         = flag.String("tls-min-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3 (defaults to 1.2)")
+	insecureFlag       = flag.Bool("insecure", false, "Skip the backend certificate verification (self-signed dev backends only), --insecure=false overrides the profile")
+	verboseFlag        = flag.Bool("v", false, "Log method, URL, status and latency of every backend call to stderr")
+	veryVerboseFlag    = flag.Bool("vv", false, "Log redacted headers and bodies of every backend call to stderr")
+	traceFlag          = flag.Bool("trace", false, "Same as -vv, plus DNS, connect, TLS and first byte timings of every backend call")
+	outputFlag         = flag.String("output", "", "Output format: table (default), json, ndjson, csv, yaml or template")
+	templateFlag       = flag.String("template", "", "Go text/template used to render the results (implies -output template), executed once per expense (e.g. '{{.ID}} {{.Price}}') or once per command result")
+	helpFlag           = flag.Bool("help", false, "Display a helpful message")
+)
+
+const (
+	exitCodeError       = 2
+	exitCodeNotLoggedIn = 3
+	exitCodeInterrupted = 130
+
+	// interruptGracePeriod is how long a cancelled command is given to return before the process exits
+	interruptGracePeriod = 2 * time.Second
 )
 
 func main() {
 	flag.Parse()
-	s := client.NewSwitch(*backendURIFlag)
+	authConfig := client.AuthConfig{
+		Scheme:   *authSchemeFlag,
+		Header:   *apiKeyHeaderFlag,
+		APIKey:   *apiKeyFlag,
+		Username: *basicUserFlag,
+		Password: *basicPassFlag,
+	}
+	if err := client.ReadAuthSecrets(&authConfig, *apiKeyStdinFlag, *basicPassStdinFlag); err != nil {
+		client.RenderError(os.Stderr, err)
+		os.Exit(exitCodeError)
+	}
+	// insecure is only set when the flag is given, so that the TLS setting of the profile applies otherwise
+	var insecure *bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "insecure" {
+			insecure = insecureFlag
+		}
+	})
+	verbosity := client.VerbosityQuiet
+	switch {
+	case *traceFlag:
+		verbosity = client.VerbosityTrace
+	case *veryVerboseFlag:
+		verbosity = client.VerbosityDump
+	case *verboseFlag:
+		verbosity = client.VerbosityInfo
+	}
+	opts := client.Options{
+		Profile:    *profileFlag,
+		BackendURI: *backendURIFlag,
+		Auth:       authConfig,
+		HTTP: client.HTTPClientConfig{
+			Timeout: *timeoutFlag,
+			Retry: client.RetryPolicy{
+				MaxAttempts: *retriesFlag,
+				BaseDelay:   *retryDelayFlag,
+				MaxDelay:    client.DefaultRetryPolicy.MaxDelay,
+			},
+			TLS: client.TLSConfig{
+				CACert:     *caCertFlag,
+				ClientCert: *clientCertFlag,
+				ClientKey:  *clientKeyFlag,
+				MinVersion: *tlsMinFlag,
+				Insecure:   insecure,
+			},
+			Verbosity: verbosity,
+		},
+		Output:   *outputFlag,
+		Template: *templateFlag,
+	}
+	s, err := client.NewSwitch(opts)
+	if err != nil {
+		client.RenderError(os.Stderr, err)
+		os.Exit(exitCodeError)
+	}
 
-	if *helpFlag || len(os.Args) == 1 {
+	if *helpFlag || flag.NArg() == 0 {
 		s.Help()
 		return
 	}
 
-	err := s.Switch()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	restoreTerminal := saveTerminal()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+		// interactive prompts block on stdin regardless of the context,
+		// so a second signal or the grace period ends the process
+		select {
+		case <-signals:
+		case <-time.After(interruptGracePeriod):
+		}
+		restoreTerminal()
+		fmt.Fprintln(os.Stderr, "\ninterrupted")
+		os.Exit(exitCodeInterrupted)
+	}()
+
+	err = s.Switch(ctx)
+	switch {
+	case err == nil:
+		return
+	case ctx.Err() == context.Canceled:
+		fmt.Fprintln(os.Stderr, "interrupted, in-flight requests cancelled")
+		os.Exit(exitCodeInterrupted)
+	case errors.Is(err, client.ErrNotLoggedIn):
+		client.RenderError(os.Stderr, err)
+		os.Exit(exitCodeNotLoggedIn)
+	default:
+		client.RenderError(os.Stderr, err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// saveTerminal saves the state of the stdin terminal and returns the func restoring it,
+// so that the echo disabled by a password prompt is turned back on when the process is interrupted
+func saveTerminal() func() {
+	fd := int(os.Stdin.Fd())
+	state, err := term.GetState(fd)
 	if err != nil {
-		fmt.Printf("cmd switch error: %v\n", err)
-		os.Exit(2)
+		return func() {}
+	}
+	return func() {
+		_ = term.Restore(fd, state)
 	}
 }